@@ -1,9 +1,14 @@
 package customlog
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/extensions/customlogpalette/src/app/CustomLog/activity/logutil"
 	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/support/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRegister(t *testing.T) {
@@ -15,4 +20,28 @@ func TestRegister(t *testing.T) {
 }
 
 func TestEval(t *testing.T) {
+	var buf bytes.Buffer
+	logutil.RegisterSink("customlog-test", func() (logutil.Sink, error) { return logutil.NewWriterSink(&buf), nil })
+	defer logutil.CloseSinks()
+
+	act, err := New(test.NewActivityInitContext(map[string]interface{}{"sinks": "customlog-test", "levelThreshold": "INFO"}, nil))
+	require.NoError(t, err)
+
+	tc := test.NewActivityContext(act.Metadata())
+	tc.SetInput(ivLogLevel, "DEBUG")
+	tc.SetInput(ivLogInput, map[string]interface{}{"message": "filtered"})
+	before := logutil.FilteredCount()
+	done, err := act.Eval(tc)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Empty(t, buf.String())
+	assert.Equal(t, before+1, logutil.FilteredCount())
+
+	tc.SetInput(ivLogLevel, "WARN")
+	tc.SetInput(ivLogInput, map[string]interface{}{"message": "order delayed"})
+	done, err = act.Eval(tc)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Contains(t, buf.String(), `WARN  [`)
+	assert.Contains(t, buf.String(), `a_message="order delayed"`)
 }
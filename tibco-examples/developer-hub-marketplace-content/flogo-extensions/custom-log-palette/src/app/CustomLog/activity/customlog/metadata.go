@@ -4,6 +4,13 @@ import (
 	"github.com/project-flogo/core/data/coerce"
 )
 
+type Settings struct {
+	Sinks          string `md:"sinks"`
+	LevelThreshold string `md:"levelThreshold"`
+	LogFormat      string `md:"logFormat"`
+	LoggerName     string `md:"loggerName"`
+}
+
 type Input struct {
 	LogLevel      string      `md:"Log Level"`
 	FlowInfo      bool        `md:"flowInfo"`
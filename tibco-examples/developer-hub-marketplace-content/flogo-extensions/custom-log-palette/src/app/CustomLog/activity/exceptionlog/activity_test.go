@@ -1,9 +1,15 @@
 package exceptionlog
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
+
+	"github.com/extensions/customlogpalette/src/app/CustomLog/activity/logutil"
 	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/support/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRegister(t *testing.T) {
@@ -12,3 +18,39 @@ func TestRegister(t *testing.T) {
 	act := activity.Get(ref)
 	assert.NotNil(t, act)
 }
+
+func TestEval(t *testing.T) {
+	var buf bytes.Buffer
+	logutil.RegisterSink("exceptionlog-test", func() (logutil.Sink, error) { return logutil.NewWriterSink(&buf), nil })
+	defer logutil.CloseSinks()
+
+	act, err := New(test.NewActivityInitContext(map[string]interface{}{"sinks": "exceptionlog-test", "logFormat": "JSON"}, nil))
+	require.NoError(t, err)
+
+	tc := test.NewActivityContext(act.Metadata())
+	t.Cleanup(func() { logutil.ReleaseFlowContext(tc.ActivityHost().ID()) })
+	// the context stored by Set and Log is applied to exception records
+	logutil.SetFlowVariable(tc, logutil.CustomFlowInfoKey, map[string]interface{}{"correlationId": "c-1", "orderId": "o-9"})
+	tc.SetInput(ivMessage, "payment failed")
+	tc.SetInput(ivExceptionLogInput, map[string]interface{}{"errorCode": "PAY-7", "errorMessage": "card declined"})
+	done, err := act.Eval(tc)
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record), buf.String())
+	// errorMessage is its own field and no longer replaces message
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "payment failed", record["a_message"])
+	assert.Equal(t, "card declined", record["a_errorMessage"])
+	assert.Equal(t, "PAY-7", record["a_errorCode"])
+	assert.Equal(t, "c-1", record["a_correlationId"])
+	assert.Equal(t, "o-9", record["a_orderId"])
+}
+
+func TestNewInvalidLogLevelEnv(t *testing.T) {
+	t.Setenv(logutil.EnvKeyLogActivityLogLevel, "VERBOSE")
+	_, err := New(test.NewActivityInitContext(map[string]interface{}{"sinks": "discard"}, nil))
+	require.Error(t, err)
+	assert.Equal(t, logutil.ErrCodeInvalidLogLevel, err.(*activity.Error).Code())
+}
@@ -4,6 +4,12 @@ import (
 	"github.com/project-flogo/core/data/coerce"
 )
 
+type Settings struct {
+	Sinks      string `md:"sinks"`
+	LogFormat  string `md:"logFormat"`
+	LoggerName string `md:"loggerName"`
+}
+
 type ExceptionLogInput struct {
 	LogLevel          string      `md:"Log Level"`
 	Message           string      `md:"message"`
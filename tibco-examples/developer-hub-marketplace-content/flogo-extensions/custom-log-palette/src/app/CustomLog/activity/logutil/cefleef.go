@@ -0,0 +1,296 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-flogo/core/engine"
+)
+
+// Environment variables configuring the "cef" and "leef" formats.
+const (
+	EnvKeyCEFVendor   = "FLOGO_CUSTOMLOG_CEF_VENDOR"
+	EnvKeyCEFProduct  = "FLOGO_CUSTOMLOG_CEF_PRODUCT"
+	EnvKeyCEFVersion  = "FLOGO_CUSTOMLOG_CEF_VERSION"
+	EnvKeyCEFMapping  = "FLOGO_CUSTOMLOG_CEF_MAPPING"
+	EnvKeyLEEFVendor  = "FLOGO_CUSTOMLOG_LEEF_VENDOR"
+	EnvKeyLEEFProduct = "FLOGO_CUSTOMLOG_LEEF_PRODUCT"
+	EnvKeyLEEFVersion = "FLOGO_CUSTOMLOG_LEEF_VERSION"
+	EnvKeyLEEFMapping = "FLOGO_CUSTOMLOG_LEEF_MAPPING"
+)
+
+// Default device header fields of the CEF and LEEF formats.
+const (
+	DefaultSIEMVendor  = "TIBCO"
+	DefaultSIEMProduct = "Flogo"
+)
+
+// defaultCEFMapping maps the standard keys to CEF extension keys. Keys without a
+// mapping are written under their own name (letters and digits only).
+var defaultCEFMapping = map[string]string{
+	"message":         "msg",
+	"errorMessage":    "reason",
+	"applicationName": "dproc",
+	"hostName":        "dvchost",
+	"pid":             "dvcpid",
+	"processName":     "cs1",
+	"jobId":           "cs2",
+	"correlationId":   "cs3",
+	"errorData":       "cs4",
+	"activityName":    "cs5",
+	"traceID":         "cs6",
+}
+
+// defaultLEEFMapping maps the standard keys to LEEF attributes.
+var defaultLEEFMapping = map[string]string{
+	"hostName": "identHostName",
+}
+
+// siemSkipped are keys carried in the header (errorCode is the event ID) or that only select the output.
+var siemSkipped = map[string]bool{"level": true, "timeStamp": true, "logFormat": true, "errorCode": true}
+
+// cefLabelPattern matches the CEF custom fields that take a companion "<key>Label" extension.
+var cefLabelPattern = regexp.MustCompile(`^(cs|cn|cfp|flexString|flexNumber|flexDate|deviceCustomDate)[0-9]+$`)
+
+// SIEMConfig configures the CEF or LEEF device header and the key mapping.
+type SIEMConfig struct {
+	Vendor  string
+	Product string
+	Version string
+	// Mapping maps logData keys to extension keys (CEF) or attributes (LEEF);
+	// mapping a key to "" drops it.
+	Mapping map[string]string
+}
+
+// CEFConfigFromEnv reads the CEF configuration from FLOGO_CUSTOMLOG_CEF_* variables.
+func CEFConfigFromEnv() (SIEMConfig, error) {
+	return siemConfigFromEnv(EnvKeyCEFVendor, EnvKeyCEFProduct, EnvKeyCEFVersion, EnvKeyCEFMapping, defaultCEFMapping)
+}
+
+// LEEFConfigFromEnv reads the LEEF configuration from FLOGO_CUSTOMLOG_LEEF_* variables.
+func LEEFConfigFromEnv() (SIEMConfig, error) {
+	return siemConfigFromEnv(EnvKeyLEEFVendor, EnvKeyLEEFProduct, EnvKeyLEEFVersion, EnvKeyLEEFMapping, defaultLEEFMapping)
+}
+
+// siemConfigFromEnv applies the mapping variable ("key=target,key2=target2") over defaults.
+// A pair without '=' or key is an error; the returned config then keeps the default mapping.
+func siemConfigFromEnv(vendorKey, productKey, versionKey, mappingKey string, defaults map[string]string) (SIEMConfig, error) {
+	cfg := SIEMConfig{
+		Vendor:  os.Getenv(vendorKey),
+		Product: os.Getenv(productKey),
+		Version: os.Getenv(versionKey),
+		Mapping: make(map[string]string, len(defaults)),
+	}
+	if cfg.Vendor == "" {
+		cfg.Vendor = DefaultSIEMVendor
+	}
+	if cfg.Product == "" {
+		cfg.Product = DefaultSIEMProduct
+	}
+	if cfg.Version == "" {
+		cfg.Version = engine.GetAppVersion()
+	}
+	for k, v := range defaults {
+		cfg.Mapping[k] = v
+	}
+	overrides := make(map[string]string)
+	for _, pair := range splitList(os.Getenv(mappingKey)) {
+		k, v, ok := strings.Cut(pair, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			return cfg, fmt.Errorf("invalid %s entry [%s], expected key=target", mappingKey, pair)
+		}
+		overrides[k] = strings.TrimSpace(v)
+	}
+	for k, v := range overrides {
+		cfg.Mapping[k] = v
+	}
+	return cfg, nil
+}
+
+var (
+	siemConfigOnce sync.Once
+	cefConfig      SIEMConfig
+	leefConfig     SIEMConfig
+)
+
+func defaultSIEMConfigs() (SIEMConfig, SIEMConfig) {
+	siemConfigOnce.Do(func() {
+		// an invalid mapping (reported by ActivityConfig.Validate) keeps the defaults
+		cefConfig, _ = CEFConfigFromEnv()
+		leefConfig, _ = LEEFConfigFromEnv()
+	})
+	return cefConfig, leefConfig
+}
+
+// SIEMSeverity maps a Custom Log level to the 0-10 CEF/LEEF severity scale.
+func SIEMSeverity(level string) int {
+	switch strings.ToUpper(level) {
+	case "ERROR":
+		return 8
+	case "WARN":
+		return 6
+	case "INFO":
+		return 3
+	case "DEBUG":
+		return 1
+	}
+	return 5
+}
+
+// siemEventID is the signature/event ID: errorCode if present, else the level.
+func siemEventID(logData map[string]interface{}, levelUpper string) string {
+	if code := toString(logData["errorCode"]); code != "" {
+		return code
+	}
+	return levelUpper
+}
+
+// formatCEF writes an ArcSight CEF:0 event:
+// CEF:0|Vendor|Product|Version|SignatureID|Name|Severity|rt=... cat=<logger> key=value ...
+func formatCEF(logData map[string]interface{}, levelUpper string, loggerName string, now time.Time, cfg SIEMConfig) string {
+	name := toString(logData["errorMessage"])
+	if name == "" {
+		name = toString(logData["message"])
+	}
+	var b strings.Builder
+	b.Grow(1024)
+	b.WriteString("CEF:0|")
+	for _, field := range []string{cfg.Vendor, cfg.Product, cfg.Version, siemEventID(logData, levelUpper), name, strconv.Itoa(SIEMSeverity(levelUpper))} {
+		b.WriteString(escapeSIEMHeader(field))
+		b.WriteByte('|')
+	}
+	b.WriteString("rt=")
+	b.WriteString(strconv.FormatInt(now.UnixMilli(), 10))
+	writeCEFExtension(&b, "cat", loggerName, "")
+	used := map[string]bool{"rt": true, "cat": true}
+	forEachSIEMField(logData, cfg, used, func(target, value, key string) {
+		if writeCEFExtension(&b, target, value, key) {
+			used[target+"Label"] = true
+		}
+	})
+	return b.String()
+}
+
+// writeCEFExtension writes key=value and reports whether it also wrote a "<key>Label" extension.
+func writeCEFExtension(b *strings.Builder, key, value, label string) bool {
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(escapeCEFValue(value))
+	if label == "" || !cefLabelPattern.MatchString(key) {
+		return false
+	}
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteString("Label=")
+	b.WriteString(escapeCEFValue(label))
+	return true
+}
+
+// formatLEEF writes a QRadar LEEF:1.0 event with tab-separated attributes:
+// LEEF:1.0|Vendor|Product|Version|EventID|devTime=...<tab>sev=...<tab>cat=<level><tab>logger=...<tab>key=value
+func formatLEEF(logData map[string]interface{}, levelUpper string, loggerName string, now time.Time, cfg SIEMConfig) string {
+	var b strings.Builder
+	b.Grow(1024)
+	b.WriteString("LEEF:1.0|")
+	for _, field := range []string{cfg.Vendor, cfg.Product, cfg.Version, siemEventID(logData, levelUpper)} {
+		b.WriteString(escapeSIEMHeader(field))
+		b.WriteByte('|')
+	}
+	// devTime uses the LEEF default format, so no devTimeFormat attribute is needed.
+	b.WriteString("devTime=")
+	b.WriteString(escapeLEEFValue(now.In(defaultTimestampConfig().location()).Format("Jan 02 2006 15:04:05.000 MST")))
+	b.WriteString("\tsev=")
+	b.WriteString(strconv.Itoa(SIEMSeverity(levelUpper)))
+	b.WriteString("\tcat=")
+	b.WriteString(levelUpper)
+	b.WriteString("\tlogger=")
+	b.WriteString(escapeLEEFValue(loggerName))
+	used := map[string]bool{"devTime": true, "sev": true, "cat": true, "logger": true}
+	forEachSIEMField(logData, cfg, used, func(target, value, key string) {
+		b.WriteByte('\t')
+		b.WriteString(target)
+		b.WriteByte('=')
+		b.WriteString(escapeLEEFValue(value))
+	})
+	return b.String()
+}
+
+// forEachSIEMField calls fn for every non-empty, non-header key in OrderedKeys order,
+// with the mapped extension key or attribute name. used holds the names the formatter
+// already wrote; a name taken before (e.g. a custom "rt", or "order-id" after "order_id")
+// gets the first free numeric suffix, so no key is written twice.
+func forEachSIEMField(logData map[string]interface{}, cfg SIEMConfig, used map[string]bool, fn func(target, value, key string)) {
+	for _, k := range OrderedKeys(logData) {
+		if siemSkipped[k] {
+			continue
+		}
+		v := toString(logData[k])
+		if v == "" {
+			continue
+		}
+		target, mapped := cfg.Mapping[k]
+		if !mapped {
+			target = k
+		}
+		if target = siemKey(target); target == "" {
+			continue
+		}
+		if used[target] {
+			n := 2
+			for used[target+strconv.Itoa(n)] {
+				n++
+			}
+			target += strconv.Itoa(n)
+		}
+		used[target] = true
+		fn(target, v, k)
+	}
+}
+
+// escapeSIEMHeader escapes '\' and '|' in a CEF/LEEF header field and flattens line breaks.
+// '=' needs no escaping in the header.
+func escapeSIEMHeader(s string) string {
+	if !strings.ContainsAny(s, "\\|\r\n") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+	return r.Replace(s)
+}
+
+var cefValueReplacer = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+
+// escapeCEFValue escapes '\' and '=' in an extension value and encodes line breaks.
+func escapeCEFValue(s string) string {
+	if !strings.ContainsAny(s, "\\=\r\n") {
+		return s
+	}
+	return cefValueReplacer.Replace(s)
+}
+
+var leefValueReplacer = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+
+// escapeLEEFValue escapes '\', the tab delimiter and line breaks in an attribute value.
+func escapeLEEFValue(s string) string {
+	if !strings.ContainsAny(s, "\\\t\r\n") {
+		return s
+	}
+	return leefValueReplacer.Replace(s)
+}
+
+// siemKey keeps only letters and digits, as CEF extension keys and LEEF attributes allow.
+func siemKey(k string) string {
+	b := make([]byte, 0, len(k))
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
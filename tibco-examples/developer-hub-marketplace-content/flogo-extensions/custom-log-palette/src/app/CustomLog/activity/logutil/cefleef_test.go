@@ -0,0 +1,118 @@
+package logutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var siemTestData = map[string]interface{}{
+	"applicationName": "Order App",
+	"processName":     "ProcessOrder",
+	"level":           "Error",
+	"timeStamp":       "2026-03-01T10:15:30.123456",
+	"message":         "payment failed",
+	"errorCode":       "PAY|7",
+	"errorMessage":    `card=declined \ retry`,
+	"errorData":       "at Pay()\nat Order()",
+	"tenant id":       "acme",
+}
+
+var siemTestConfig = SIEMConfig{
+	Vendor:  `TIB|CO`,
+	Product: `Flogo\Apps`,
+	Version: "1=2",
+	Mapping: map[string]string{"message": "msg", "errorMessage": "reason", "errorData": "cs4", "processName": "cs1", "applicationName": ""},
+}
+
+func TestFormatCEF(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 15, 30, 123000000, time.UTC)
+	line := formatCEF(siemTestData, "ERROR", "flogo.orders", now, siemTestConfig)
+	assert.Equal(t,
+		`CEF:0|TIB\|CO|Flogo\\Apps|1=2|PAY\|7|card=declined \\ retry|8|`+
+			`rt=1772360130123 cat=flogo.orders cs1=ProcessOrder cs1Label=processName msg=payment failed `+
+			`reason=card\=declined \\ retry cs4=at Pay()\nat Order() cs4Label=errorData tenantid=acme`,
+		line)
+}
+
+func TestFormatLEEF(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 15, 30, 123000000, time.UTC)
+	cfg := siemTestConfig
+	cfg.Mapping = map[string]string{"processName": "flow"}
+	line := formatLEEF(siemTestData, "ERROR", "flogo.orders", now, cfg)
+	assert.Equal(t, []string{
+		`LEEF:1.0|TIB\|CO|Flogo\\Apps|1=2|PAY\|7|devTime=Mar 01 2026 10:15:30.123 UTC`,
+		"sev=8", "cat=ERROR", "logger=flogo.orders",
+		"applicationName=Order App", "flow=ProcessOrder", "message=payment failed",
+		`errorMessage=card=declined \\ retry`, `errorData=at Pay()\nat Order()`, "tenantid=acme",
+	}, strings.Split(line, "\t"))
+}
+
+func TestSIEMFieldCollisions(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 15, 30, 123000000, time.UTC)
+	data := map[string]interface{}{
+		"processName": "ProcessOrder",
+		"message":     "payment failed",
+		"msg":         "m-1",
+		"rt":          "r-1",
+		"cat":         "c-1",
+		"order-id":    "o-1",
+		"order_id":    "o-2",
+		"cs1Label":    "l-1",
+	}
+	cfg := siemTestConfig
+	cfg.Mapping = map[string]string{"message": "msg", "processName": "cs1"}
+
+	// a custom key never repeats a key the formatter wrote, and keys that only differ in
+	// removed characters stay apart
+	line := formatCEF(data, "ERROR", "flogo.orders", now, cfg)
+	fields := strings.Split(line[strings.LastIndex(line, "|")+1:], " ")
+	assert.Contains(t, fields, "cs1Label=processName")
+	assert.Contains(t, fields, "cs1Label2=l-1")
+	assert.Contains(t, fields, "cat2=c-1")
+	assert.Contains(t, line, " msg=payment failed ")
+	assert.Contains(t, fields, "msg2=m-1")
+	assert.Contains(t, fields, "rt2=r-1")
+	assert.Contains(t, fields, "orderid=o-1")
+	assert.Contains(t, fields, "orderid2=o-2")
+
+	line = formatLEEF(data, "ERROR", "flogo.orders", now, cfg)
+	attrs := strings.Split(line, "\t")
+	assert.Contains(t, attrs, "cat=ERROR")
+	assert.Contains(t, attrs, "cat2=c-1")
+	assert.Contains(t, attrs, "msg=payment failed")
+	assert.Contains(t, attrs, "msg2=m-1")
+	assert.Contains(t, attrs, "rt=r-1")
+	assert.Contains(t, attrs, "orderid=o-1")
+	assert.Contains(t, attrs, "orderid2=o-2")
+}
+
+func TestSIEMConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyCEFVendor, "Acme")
+	t.Setenv(EnvKeyCEFVersion, "2.1")
+	t.Setenv(EnvKeyCEFMapping, "correlationId=externalId, traceID= ,")
+	cfg, err := CEFConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", cfg.Vendor)
+	assert.Equal(t, DefaultSIEMProduct, cfg.Product)
+	assert.Equal(t, "2.1", cfg.Version)
+	assert.Equal(t, "externalId", cfg.Mapping["correlationId"])
+	assert.Equal(t, "", cfg.Mapping["traceID"])
+	assert.Equal(t, "msg", cfg.Mapping["message"])
+
+	// a pair without '=' fails validation and keeps the default mapping
+	t.Setenv(EnvKeyCEFMapping, "correlationId=externalId,bogus")
+	cfg, err = CEFConfigFromEnv()
+	assert.Error(t, err)
+	assert.Equal(t, defaultCEFMapping["correlationId"], cfg.Mapping["correlationId"])
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidLogFormat)
+
+	t.Setenv(EnvKeyCEFMapping, "")
+	t.Setenv(EnvKeyLEEFMapping, "=usrName")
+	_, err = LEEFConfigFromEnv()
+	assert.Error(t, err)
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidLogFormat)
+}
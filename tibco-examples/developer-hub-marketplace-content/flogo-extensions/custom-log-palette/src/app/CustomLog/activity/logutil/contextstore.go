@@ -0,0 +1,145 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/support/log"
+)
+
+// EnvKeyContextTTL bounds how long the context store keeps the flow variables of a flow
+// instance after its last use (Go duration, default DefaultContextTTL).
+const EnvKeyContextTTL = "FLOGO_CUSTOMLOG_CONTEXT_TTL"
+
+// DefaultContextTTL is the idle time after which a context store entry is dropped.
+const DefaultContextTTL = 30 * time.Minute
+
+// ContextTTLFromEnv reads FLOGO_CUSTOMLOG_CONTEXT_TTL.
+func ContextTTLFromEnv() (time.Duration, error) {
+	v := os.Getenv(EnvKeyContextTTL)
+	if v == "" {
+		return DefaultContextTTL, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return DefaultContextTTL, fmt.Errorf("invalid %s [%s]", EnvKeyContextTTL, v)
+	}
+	return d, nil
+}
+
+// contextStore holds the flow variables (customFlowInfo, ...) of hosts that are not flow
+// instances, such as test.NewActivityContext or custom action hosts, keyed by their instance ID.
+// Such hosts do not publish flow events, so an entry is released by ReleaseFlowContext or
+// after the TTL without use.
+type contextStore struct {
+	mu        sync.Mutex
+	entries   map[string]*storeEntry
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+type storeEntry struct {
+	values  map[string]interface{}
+	touched time.Time
+}
+
+var flowContexts = newContextStore()
+
+func newContextStore() *contextStore {
+	ttl, _ := ContextTTLFromEnv()
+	return &contextStore{entries: make(map[string]*storeEntry), ttl: ttl}
+}
+
+func (s *contextStore) get(flowID, name string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[flowID]
+	if !ok {
+		return nil, false
+	}
+	e.touched = Now()
+	v, ok := e.values[name]
+	return v, ok
+}
+
+func (s *contextStore) set(flowID, name string, value interface{}) {
+	now := Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	e, ok := s.entries[flowID]
+	if !ok {
+		e = &storeEntry{values: make(map[string]interface{})}
+		s.entries[flowID] = e
+	}
+	e.values[name] = value
+	e.touched = now
+}
+
+// sweep drops idle entries, at most once per TTL.
+func (s *contextStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	s.lastSweep = now
+	for id, e := range s.entries {
+		if now.Sub(e.touched) >= s.ttl {
+			delete(s.entries, id)
+		}
+	}
+}
+
+func (s *contextStore) release(flowID string) {
+	s.mu.Lock()
+	delete(s.entries, flowID)
+	s.mu.Unlock()
+}
+
+func (s *contextStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// ReleaseFlowContext drops the stored flow variables of a flow instance. Hosts that are not
+// flow instances call it when the instance completes; entries they never release are
+// dropped after FLOGO_CUSTOMLOG_CONTEXT_TTL.
+func ReleaseFlowContext(flowID string) {
+	flowContexts.release(flowID)
+}
+
+// storeScope is the data.Scope of one flow instance in the context store.
+type storeScope struct {
+	flowID string
+}
+
+func (s storeScope) GetValue(name string) (interface{}, bool) {
+	return flowContexts.get(s.flowID, name)
+}
+
+func (s storeScope) SetValue(name string, value interface{}) error {
+	flowContexts.set(s.flowID, name, value)
+	return nil
+}
+
+var noContextWarned sync.Map
+
+// warnNoContext logs a warning that the flow variables of a call cannot be stored, once
+// per host and reason.
+func warnNoContext(ctx activity.Context, reason string) {
+	key := reason
+	if host := ctx.ActivityHost(); host != nil {
+		key = host.Name() + "|" + reason
+	}
+	if _, warned := noContextWarned.LoadOrStore(key, true); warned {
+		return
+	}
+	logger := ctx.Logger()
+	if logger == nil {
+		logger = log.RootLogger()
+	}
+	logger.Warnf("customFlowInfo is unavailable: %s", reason)
+}
@@ -0,0 +1,93 @@
+package logutil
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/support/log"
+	"github.com/project-flogo/core/support/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextStoreTestHost(t *testing.T) {
+	// test.NewActivityContext is not a flow instance: values live in the context store
+	ctx := test.NewActivityContext(activity.ToMetadata())
+	t.Cleanup(func() { ReleaseFlowContext(ctx.ActivityHost().ID()) })
+	SetFlowVariable(ctx, CustomFlowInfoKey, map[string]interface{}{"correlationId": "c-1"})
+
+	other := test.NewActivityContext(activity.ToMetadata())
+	assert.Equal(t, map[string]interface{}{"correlationId": "c-1"}, CustomFlowInfo(other), "same flow instance ID")
+	v, ok := ctx.ActivityHost().Scope().GetValue("TIB_Flow:" + CustomFlowInfoKey)
+	assert.False(t, ok, "the host scope is not used")
+	assert.Nil(t, v)
+
+	ReleaseFlowContext(ctx.ActivityHost().ID())
+	assert.Empty(t, CustomFlowInfo(ctx))
+}
+
+// warnLogger records the warnings of an activity context.
+type warnLogger struct {
+	log.Logger
+	warnings []string
+}
+
+func (l *warnLogger) Warnf(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+type loggerContext struct {
+	activity.Context
+	logger log.Logger
+}
+
+func (c loggerContext) Logger() log.Logger { return c.logger }
+
+func TestContextStoreNoInstanceID(t *testing.T) {
+	noContextWarned.Clear()
+	logger := &warnLogger{Logger: log.RootLogger()}
+	ctx := loggerContext{Context: newRecordContext(""), logger: logger}
+	SetFlowVariable(ctx, CustomFlowInfoKey, map[string]interface{}{"correlationId": "c-1"})
+	_, ok := GetFlowVariable(ctx, CustomFlowInfoKey)
+	assert.False(t, ok)
+	info, err := UpdateCustomFlowInfo(ctx, ContextUpdate{Values: map[string]interface{}{"orderId": "o-1"}})
+	assertErrorCode(t, err, ErrCodeInvalidContext)
+	assert.Nil(t, info)
+	// warned once for the host, not on every call
+	assert.Equal(t, []string{"customFlowInfo is unavailable: the activity host is not a flow instance and has no instance ID"}, logger.warnings)
+}
+
+func TestContextStoreCleanup(t *testing.T) {
+	now := fixedTime()
+	SetClock(func() time.Time { return now })
+	t.Cleanup(func() { SetClock(nil) })
+	s := &contextStore{entries: make(map[string]*storeEntry), ttl: time.Minute}
+
+	s.set("a", "k", 1)
+	s.set("b", "k", 2)
+	now = now.Add(40 * time.Second)
+	s.get("a", "k")
+	now = now.Add(40 * time.Second)
+	s.set("c", "k", 3)
+	// b was idle for a full TTL; a was read 40s ago
+	assert.Equal(t, 2, s.len())
+	_, ok := s.get("b", "k")
+	assert.False(t, ok)
+
+	s.release("a")
+	assert.Equal(t, 1, s.len())
+}
+
+func TestContextTTLFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyContextTTL, "5m")
+	d, err := ContextTTLFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, d)
+
+	t.Setenv(EnvKeyContextTTL, "0s")
+	_, err = ContextTTLFromEnv()
+	assert.Error(t, err)
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidContext)
+}
@@ -8,116 +8,220 @@ import (
 	"time"
 )
 
+// Log format names accepted by FormatCustomLog (case-insensitive).
+const (
+	FormatText    = "text"
+	FormatJSON    = "json"
+	FormatLogfmt  = "logfmt"
+	FormatECS     = "ecs"
+	FormatGELF    = "gelf"
+	FormatCEF     = "cef"
+	FormatLEEF    = "leef"
+	FormatPattern = "pattern"
+)
+
+var logFormats = []string{FormatText, FormatJSON, FormatLogfmt, FormatECS, FormatGELF, FormatCEF, FormatLEEF, FormatPattern}
+
+// LogFormats returns the supported log format names.
+func LogFormats() []string {
+	return append([]string(nil), logFormats...)
+}
+
+// IsLogFormat reports whether name is a supported log format.
+func IsLogFormat(name string) bool {
+	name = normalizeFormat(name)
+	for _, f := range logFormats {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeFormat(format string) string {
+	return strings.TrimSpace(strings.ToLower(format))
+}
+
 // FormatCustomLog writes a log line in custom log format.
 // logData: map of key-value pairs (all values converted to string)
-// format: "json" for JSON output (case-insensitive, whitespace trimmed), otherwise text format
+// format: "json", "logfmt", "ecs", "gelf", "cef", "leef" or "pattern" (case-insensitive, whitespace trimmed), otherwise text format
 // level: INFO, DEBUG, ERROR, WARN
 // loggerName: full logger/class name (e.g. flogo.CustomLog.activity.customlog.app.flow.activity)
 //
-// Text format: 2026-02-13T16:53:47,498 INFO  [loggerName] - a_key1="val1", a_key2="val2"
-// JSON format: same data as JSON object with timestamp, level, logger, data
+// Text format: 2026-02-13T15:53:47.498Z INFO  [loggerName] - a_key1="val1", a_key2="val2"
+// (the timestamp follows TimestampConfig)
+// JSON format: same data as JSON object with timestamp, level, logger, data (typed with JSONConfig)
+// logfmt format: ts=2026-02-13T15:53:47.498Z level=INFO logger=loggerName a_key1=val1 a_key2="val 2"
+// ECS format: Elastic Common Schema JSON (see ECSVersion)
+// GELF format: Graylog Extended Log Format 1.1 with "_" prefixed additional fields
+// CEF / LEEF format: ArcSight CEF:0 / QRadar LEEF:1.0 events for SIEM ingestion
+// pattern format: the FLOGO_CUSTOMLOG_PATTERN layout (see Pattern)
 func FormatCustomLog(logData map[string]interface{}, format string, level string, loggerName string) string {
-	// Build ordered key list: standard keys first, then custom keys (contextParams, additionalLogParams) alphabetically
-	standardOrder := []string{
+	return formatCustomLogAt(logData, nil, format, level, loggerName, Now())
+}
+
+// formatCustomLogAt formats with the instant the record was captured, so asynchronous
+// writers do not shift the timestamp to the time of formatting. sections are the
+// record's Entry.Sections, used when FLOGO_CUSTOMLOG_KEY_SECTIONS is set.
+func formatCustomLogAt(logData map[string]interface{}, sections map[string]string, format string, level string, loggerName string, now time.Time) string {
+	levelUpper := strings.ToUpper(level)
+	names := defaultKeyNames(sections)
+	switch normalizeFormat(format) {
+	case FormatJSON:
+		return formatJSON(logData, levelUpper, loggerName, now, defaultJSONConfig(), names)
+	case FormatLogfmt:
+		return formatLogfmt(logData, levelUpper, loggerName, now, names)
+	case FormatECS:
+		return formatECS(logData, levelUpper, loggerName, now, defaultECSConfig())
+	case FormatGELF:
+		return formatGELF(logData, levelUpper, loggerName, now, defaultGELFHost())
+	case FormatCEF:
+		cef, _ := defaultSIEMConfigs()
+		return formatCEF(logData, levelUpper, loggerName, now, cef)
+	case FormatLEEF:
+		_, leef := defaultSIEMConfigs()
+		return formatLEEF(logData, levelUpper, loggerName, now, leef)
+	case FormatPattern:
+		return defaultLayout().format(logData, sections, levelUpper, loggerName, now)
+	}
+	return formatText(logData, levelUpper, loggerName, now, defaultTextConfig(), names)
+}
+
+// formatJSON writes timestamp, level and logger, then the data keys named by names.
+// With sections, the ungrouped keys are followed by one nested object per section.
+func formatJSON(logData map[string]interface{}, levelUpper string, loggerName string, now time.Time, cfg JSONConfig, names keyNames) string {
+	tsCfg := defaultTimestampConfig()
+
+	// JSON format: ordered flat structure, built with strings.Builder for fewer allocations
+	// 1. metadata | 2. tracking | 3. message | 4. standard params | 5. exception params | 6. additional
+	dataKeys := []string{
 		"applicationName", "processName", "jobId", "processInstanceId",
-		"level", "activityName", "timeStamp",
-		"sessionId", "sender", "traceID", "serviceScope", "correlationId",
-		"trackingId", "logFormat", "targetSystem", "message",
+		"parentProcessInstanceId", "rootProcessInstanceId",
+		"activityName", "sessionId", "correlationId", "trackingId",
+		"timeStamp", "level", "message",
+		"logFormat", "targetSystem",
 		"errorCode", "errorMessage", "errorData",
 	}
 	seen := make(map[string]bool)
-	var orderedKeys []string
-	for _, k := range standardOrder {
-		if _, ok := logData[k]; ok && !seen[k] {
-			orderedKeys = append(orderedKeys, k)
-			seen[k] = true
+	var b strings.Builder
+	b.Grow(1024)
+	appendKey := func(key string) {
+		if s := b.String(); s[len(s)-1] != '{' {
+			b.WriteByte(',')
 		}
+		writeJSONString(&b, key)
+		b.WriteByte(':')
 	}
-	var customKeys []string
-	for k := range logData {
-		if !seen[k] {
-			customKeys = append(customKeys, k)
+	appendJSONPair := func(key, val string) {
+		if val == "" {
+			return
 		}
+		appendKey(key)
+		writeJSONString(&b, val)
 	}
-	sort.Strings(customKeys)
-	orderedKeys = append(orderedKeys, customKeys...)
-
-	now := time.Now()
-	timestamp := now.Format("2006-01-02T15:04:05.000000")
-	timestampShort := now.Format("2006-01-02T15:04:05") + "," + fmt.Sprintf("%03d", now.Nanosecond()/1000000)
-	levelUpper := strings.ToUpper(level)
-
-	if strings.TrimSpace(strings.ToLower(format)) == "json" {
-		// JSON format: ordered flat structure, built with strings.Builder for fewer allocations
-		// 1. metadata | 2. tracking | 3. message | 4. standard params | 5. exception params | 6. additional
-		dataKeys := []string{
-			"applicationName", "processName", "jobId", "processInstanceId",
-			"activityName", "sessionId", "correlationId", "trackingId",
-			"timeStamp", "level", "message",
-			"logFormat", "targetSystem",
-			"errorCode", "errorMessage", "errorData",
+	written := func(k string) bool {
+		if !cfg.Typed {
+			return toString(logData[k]) != ""
 		}
-		outputKeys := []string{
-			"a_applicationName", "a_processName", "a_jobId", "a_processInstanceId",
-			"a_activityName", "a_sessionId", "a_correlationId", "a_trackingId",
-			"a_timeStamp", "a_level", "a_message",
-			"a_logFormat", "a_targetSystem",
-			"a_errorCode", "a_errorMessage", "a_errorData",
+		return cfg.KeepEmpty || !isEmptyJSONValue(logData[k])
+	}
+	appendData := func(key, k string) {
+		if !written(k) {
+			return
 		}
-		seen := make(map[string]bool)
-		var b strings.Builder
-		b.Grow(1024)
-		appendJSONPair := func(key, val string) {
-			if val == "" {
-				return
-			}
-			if b.Len() > 1 {
-				b.WriteByte(',')
-			}
-			escaped, _ := json.Marshal(key)
-			b.Write(escaped)
-			b.WriteByte(':')
-			escaped, _ = json.Marshal(val)
-			b.Write(escaped)
+		appendKey(key)
+		if cfg.Typed {
+			writeJSONTyped(&b, logData[k], cfg.maxDepth())
+		} else {
+			writeJSONString(&b, toString(logData[k]))
 		}
-		b.WriteByte('{')
-		appendJSONPair("timestamp", timestamp)
-		appendJSONPair("level", levelUpper)
-		appendJSONPair("logger", loggerName)
-		for i, k := range dataKeys {
-			if v, ok := logData[k]; ok {
-				s := toString(v)
-				if s != "" {
-					appendJSONPair(outputKeys[i], s)
-					seen[k] = true
-				}
-			}
+	}
+	b.WriteByte('{')
+	if cfg.Typed && (tsCfg.Style == TimeEpochMillis || tsCfg.Style == TimeEpochNanos) {
+		appendKey("timestamp")
+		b.WriteString(tsCfg.Format(now))
+	} else {
+		appendJSONPair("timestamp", tsCfg.Format(now))
+	}
+	appendJSONPair("level", levelUpper)
+	appendJSONPair("logger", loggerName)
+	keys := make([]string, 0, len(logData))
+	for _, k := range dataKeys {
+		if _, ok := logData[k]; ok {
+			keys = append(keys, k)
+			seen[k] = true
 		}
-		for _, k := range orderedKeys {
-			if seen[k] {
-				continue
+	}
+	for _, k := range OrderedKeys(logData) {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	open := ""
+	for _, k := range names.order(keys) {
+		section, leaf := names.split(k)
+		if section == "" {
+			// with an empty prefix, a data key named like a record key is left out
+			if leaf != "timestamp" && leaf != "level" && leaf != "logger" {
+				appendData(leaf, k)
 			}
-			s := toString(logData[k])
-			if s != "" {
-				appendJSONPair("a_"+k, s)
+			continue
+		}
+		if !written(k) {
+			continue
+		}
+		if section != open {
+			if open != "" {
+				b.WriteByte('}')
 			}
+			appendKey(section)
+			b.WriteByte('{')
+			open = section
 		}
+		appendData(leaf, k)
+	}
+	if open != "" {
 		b.WriteByte('}')
-		return b.String()
 	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatText(logData map[string]interface{}, levelUpper string, loggerName string, now time.Time, cfg TextConfig, names keyNames) string {
+	return fmt.Sprintf("%s %-5s [%s] - %s",
+		defaultTimestampConfig().formatText(now), levelUpper, loggerName, textPairs(logData, cfg, names))
+}
+
+// standardOrder is the output order of the standard keys in the text format.
+var standardOrder = []string{
+	"applicationName", "processName", "jobId", "processInstanceId",
+	"parentProcessInstanceId", "rootProcessInstanceId",
+	"level", "activityName", "timeStamp",
+	"sessionId", "sender", "traceID", "spanID", "traceFlags", "traceState",
+	"serviceScope", "correlationId",
+	"trackingId", "logFormat", "targetSystem", "message",
+	"errorCode", "errorMessage", "errorData",
+}
 
-	// Text format (skip empty values)
-	var pairs []string
-	for _, k := range orderedKeys {
-		v := logData[k]
-		s := toString(v)
-		if s != "" {
-			pairs = append(pairs, fmt.Sprintf("a_%s=%q", k, s))
+// OrderedKeys returns the keys of logData in output order:
+// standard keys first, then custom keys (contextParams, additionalLogParams) alphabetically.
+func OrderedKeys(logData map[string]interface{}) []string {
+	orderedKeys := make([]string, 0, len(logData))
+	seen := make(map[string]bool, len(logData))
+	for _, k := range standardOrder {
+		if _, ok := logData[k]; ok && !seen[k] {
+			orderedKeys = append(orderedKeys, k)
+			seen[k] = true
 		}
 	}
-	msgPart := strings.Join(pairs, ", ")
-	return fmt.Sprintf("%s %-5s [%s] - %s",
-		timestampShort, levelUpper, loggerName, msgPart)
+	var customKeys []string
+	for k := range logData {
+		if !seen[k] {
+			customKeys = append(customKeys, k)
+		}
+	}
+	sort.Strings(customKeys)
+	return append(orderedKeys, customKeys...)
 }
 
 // BuildCustomFlowInfoMap builds a map with all Header fields + contextParams keyValuePair.
@@ -317,7 +421,12 @@ func toString(v interface{}) string {
 		return fmt.Sprintf("%g", x)
 	case bool:
 		return fmt.Sprintf("%t", x)
-	default:
-		return fmt.Sprintf("%v", v)
 	}
+	if isComplexValue(v) {
+		// maps, slices and structs as compact JSON rather than Go's map[k:v] form
+		var b strings.Builder
+		writeJSONTyped(&b, v, DefaultJSONMaxDepth)
+		return b.String()
+	}
+	return fmt.Sprintf("%v", v)
 }
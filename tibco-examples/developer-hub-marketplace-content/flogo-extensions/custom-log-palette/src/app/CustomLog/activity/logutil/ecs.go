@@ -0,0 +1,212 @@
+package logutil
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ECSVersion is the Elastic Common Schema release the "ecs" format is written against.
+const ECSVersion = "8.11.0"
+
+// Environment variables configuring the "ecs" format.
+const (
+	EnvKeyECSNamespace = "FLOGO_CUSTOMLOG_ECS_NAMESPACE"
+	EnvKeyECSDataset   = "FLOGO_CUSTOMLOG_ECS_DATASET"
+)
+
+// DefaultECSNamespace holds the custom keys (contextParams, additionalLogParams, ...).
+const DefaultECSNamespace = "labels"
+
+// ecsFields maps the standard keys to ECS fields.
+var ecsFields = map[string]string{
+	"applicationName": "service.name",
+	"appVersion":      "service.version",
+	"environment":     "service.environment",
+	"traceID":         "trace.id",
+	"spanID":          "span.id",
+	"errorCode":       "error.code",
+	"errorMessage":    "error.message",
+	"errorData":       "error.stack_trace",
+	"hostName":        "host.hostname",
+	"pid":             "process.pid",
+	"containerId":     "container.id",
+	"podName":         "orchestrator.resource.name",
+	"namespace":       "orchestrator.namespace",
+}
+
+// ecsFlogoFields are standard keys without an ECS equivalent; they are written under "flogo".
+var ecsFlogoFields = map[string]string{
+	"processName":       "flogo.flow.name",
+	"jobId":             "flogo.flow.job_id",
+	"processInstanceId": "flogo.flow.instance_id",
+	"activityName":      "flogo.activity.name",
+	"sessionId":         "flogo.session_id",
+	"correlationId":     "flogo.correlation_id",
+	"trackingId":        "flogo.tracking_id",
+	"sender":            "flogo.sender",
+	"serviceScope":      "flogo.service_scope",
+	"targetSystem":      "flogo.target_system",
+	"nodeName":          "flogo.node.name",
+	"traceFlags":        "flogo.trace_flags",
+	"traceState":        "flogo.trace_state",
+}
+
+// ecsSkipped are keys already written as @timestamp, log.level or message, or that only select the output.
+var ecsSkipped = map[string]bool{"timeStamp": true, "level": true, "message": true, "logFormat": true, "loggerName": true}
+
+// ECSConfig configures the "ecs" format.
+type ECSConfig struct {
+	// Namespace is the object holding custom keys (default "labels").
+	Namespace string
+	// Dataset is written as event.dataset (default: the service name).
+	Dataset string
+}
+
+var ecsNamespacePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+var (
+	ecsConfigOnce sync.Once
+	ecsConfig     ECSConfig
+)
+
+// ECSConfigFromEnv reads the format configuration from FLOGO_CUSTOMLOG_ECS_* variables.
+// An invalid namespace falls back to DefaultECSNamespace.
+func ECSConfigFromEnv() ECSConfig {
+	cfg := ECSConfig{
+		Namespace: strings.TrimSpace(os.Getenv(EnvKeyECSNamespace)),
+		Dataset:   strings.TrimSpace(os.Getenv(EnvKeyECSDataset)),
+	}
+	if !ecsNamespacePattern.MatchString(cfg.Namespace) {
+		cfg.Namespace = DefaultECSNamespace
+	}
+	return cfg
+}
+
+func defaultECSConfig() ECSConfig {
+	ecsConfigOnce.Do(func() {
+		ecsConfig = ECSConfigFromEnv()
+	})
+	return ecsConfig
+}
+
+// formatECS writes an ECS JSON document. Following the ecs-logging spec, @timestamp,
+// log.level, message and ecs.version come first as dotted keys; the remaining fields are
+// nested objects in the order of OrderedKeys. Custom keys are flat fields of the namespace.
+func formatECS(logData map[string]interface{}, levelUpper string, loggerName string, now time.Time, cfg ECSConfig) string {
+	doc := &ecsNode{}
+	doc.set("log.logger", loggerName)
+	serviceName := toString(logData["applicationName"])
+	for _, k := range OrderedKeys(logData) {
+		if ecsSkipped[k] {
+			continue
+		}
+		v := logData[k]
+		if toString(v) == "" {
+			continue
+		}
+		if field, ok := ecsFields[k]; ok {
+			doc.set(field, v)
+		} else if field, ok := ecsFlogoFields[k]; ok {
+			doc.set(field, v)
+		} else {
+			// custom keys stay flat fields of the namespace (ECS labels cannot be objects),
+			// so a dotted key such as baggage.tenant becomes baggage_tenant
+			doc.set(cfg.Namespace+"."+strings.ReplaceAll(k, ".", "_"), v)
+		}
+	}
+	if _, ok := logData["podName"]; ok {
+		doc.set("orchestrator.type", "kubernetes")
+		doc.set("orchestrator.resource.type", "pod")
+	}
+	dataset := cfg.Dataset
+	if dataset == "" {
+		dataset = serviceName
+	}
+	doc.set("event.dataset", dataset)
+
+	var b strings.Builder
+	b.Grow(1024)
+	b.WriteByte('{')
+	writeJSONString(&b, "@timestamp")
+	b.WriteByte(':')
+	writeJSONString(&b, now.UTC().Format("2006-01-02T15:04:05.000Z"))
+	b.WriteString(`,"log.level":`)
+	writeJSONString(&b, levelUpper)
+	b.WriteString(`,"message":`)
+	writeJSONString(&b, toString(logData["message"]))
+	b.WriteString(`,"ecs.version":`)
+	writeJSONString(&b, ECSVersion)
+	for _, c := range doc.children {
+		b.WriteByte(',')
+		c.write(&b)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// ecsNode is an object of the ECS document that keeps its fields in insertion order.
+type ecsNode struct {
+	key      string
+	value    interface{}
+	children []*ecsNode
+}
+
+// set stores value at a dotted path, creating intermediate objects. A value already
+// stored at a prefix of the path is replaced by an object.
+func (n *ecsNode) set(path string, value interface{}) {
+	for _, part := range strings.Split(path, ".") {
+		var child *ecsNode
+		for _, c := range n.children {
+			if c.key == part {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			child = &ecsNode{key: part}
+			n.children = append(n.children, child)
+		}
+		child.value = nil
+		n = child
+	}
+	n.value = value
+	n.children = nil
+}
+
+func (n *ecsNode) write(b *strings.Builder) {
+	writeJSONString(b, n.key)
+	b.WriteByte(':')
+	if n.children == nil {
+		writeTypedJSONValue(b, n.value)
+		return
+	}
+	b.WriteByte('{')
+	for i, c := range n.children {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		c.write(b)
+	}
+	b.WriteByte('}')
+}
+
+// writeTypedJSONValue keeps numbers and booleans typed (e.g. process.pid); everything else is a string.
+func writeTypedJSONValue(b *strings.Builder, v interface{}) {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+		if out, err := json.Marshal(v); err == nil {
+			b.Write(out)
+			return
+		}
+	}
+	writeJSONString(b, toString(v))
+}
+
+func writeJSONString(b *strings.Builder, s string) {
+	out, _ := json.Marshal(s)
+	b.Write(out)
+}
@@ -0,0 +1,85 @@
+package logutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatECS(t *testing.T) {
+	logData := map[string]interface{}{
+		"applicationName": "orders",
+		"processName":     "Create Order",
+		"jobId":           "4711",
+		"activityName":    "LogError",
+		"level":           "Error",
+		"timeStamp":       "2026-02-13T16:53:47.498000",
+		"message":         "payment failed",
+		"traceID":         "4bf92f3577b34da6a3ce929d0e0e4736",
+		"errorCode":       "PAY-7",
+		"errorMessage":    "card declined",
+		"errorData":       "at Pay()\nat Order()",
+		"correlationId":   "c-1",
+		"pid":             4242,
+		"podName":         "orders-7d9f",
+		"orderId":         "o-9",
+		"region":          "eu",
+		"baggage.tenant":  "acme",
+		"empty":           "",
+	}
+	now := time.Date(2026, 2, 13, 16, 53, 47, 498000000, time.FixedZone("CET", 3600))
+	line := formatECS(logData, "ERROR", "flogo.orders", now, ECSConfig{Namespace: DefaultECSNamespace})
+
+	assert.True(t, strings.HasPrefix(line, `{"@timestamp":"2026-02-13T15:53:47.498Z","log.level":"ERROR","message":"payment failed","ecs.version":"`+ECSVersion+`",`), line)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &doc))
+	field := func(path string) interface{} {
+		var v interface{} = doc
+		for _, p := range strings.Split(path, ".") {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			v = m[p]
+		}
+		return v
+	}
+	assert.Equal(t, "flogo.orders", field("log.logger"))
+	assert.Equal(t, "orders", field("service.name"))
+	assert.Equal(t, "orders", field("event.dataset"))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", field("trace.id"))
+	assert.Equal(t, "PAY-7", field("error.code"))
+	assert.Equal(t, "card declined", field("error.message"))
+	assert.Equal(t, "at Pay()\nat Order()", field("error.stack_trace"))
+	assert.Equal(t, float64(4242), field("process.pid"))
+	assert.Equal(t, "orders-7d9f", field("orchestrator.resource.name"))
+	assert.Equal(t, "kubernetes", field("orchestrator.type"))
+	assert.Equal(t, "Create Order", field("flogo.flow.name"))
+	assert.Equal(t, "c-1", field("flogo.correlation_id"))
+	// dotted custom keys do not nest: labels only holds flat values
+	assert.Equal(t, map[string]interface{}{"orderId": "o-9", "region": "eu", "baggage_tenant": "acme"}, field("labels"))
+	assert.NotContains(t, doc, "timeStamp")
+	assert.NotContains(t, doc, "level")
+
+	line = formatECS(logData, "ERROR", "flogo.orders", now, ECSConfig{Namespace: "orders.custom", Dataset: "orders.audit"})
+	doc = nil
+	require.NoError(t, json.Unmarshal([]byte(line), &doc))
+	assert.Equal(t, "orders.audit", field("event.dataset"))
+	assert.Equal(t, "eu", field("orders.custom.region"))
+	assert.Equal(t, "acme", field("orders.custom.baggage_tenant"))
+	assert.Nil(t, field("labels"))
+}
+
+func TestECSConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyECSNamespace, "custom")
+	t.Setenv(EnvKeyECSDataset, "orders.log")
+	assert.Equal(t, ECSConfig{Namespace: "custom", Dataset: "orders.log"}, ECSConfigFromEnv())
+
+	t.Setenv(EnvKeyECSNamespace, "bad namespace")
+	assert.Equal(t, DefaultECSNamespace, ECSConfigFromEnv().Namespace)
+}
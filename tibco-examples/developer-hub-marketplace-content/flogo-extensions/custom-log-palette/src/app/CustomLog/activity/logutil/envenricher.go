@@ -0,0 +1,179 @@
+package logutil
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/project-flogo/core/engine"
+)
+
+// EnvKeyEnvFields selects the environment fields added to every record
+// (comma-separated field names or "all"; unset adds none).
+const EnvKeyEnvFields = "FLOGO_CUSTOMLOG_ENV_FIELDS"
+
+// Environment field names accepted in FLOGO_CUSTOMLOG_ENV_FIELDS.
+const (
+	EnvFieldHost       = "host"
+	EnvFieldPID        = "pid"
+	EnvFieldContainer  = "container"
+	EnvFieldPod        = "pod"
+	EnvFieldNamespace  = "namespace"
+	EnvFieldNode       = "node"
+	EnvFieldAppVersion = "appVersion"
+	EnvFieldEnvName    = "envName"
+)
+
+// Downward-API environment variables read for the Kubernetes fields.
+const (
+	EnvKeyPodName      = "POD_NAME"
+	EnvKeyPodNamespace = "POD_NAMESPACE"
+	EnvKeyNodeName     = "NODE_NAME"
+)
+
+// envFieldKeys maps each environment field to the logData key it is written to.
+var envFieldKeys = map[string]string{
+	EnvFieldHost:       "hostName",
+	EnvFieldPID:        "pid",
+	EnvFieldContainer:  "containerId",
+	EnvFieldPod:        "podName",
+	EnvFieldNamespace:  "namespace",
+	EnvFieldNode:       "nodeName",
+	EnvFieldAppVersion: "appVersion",
+	EnvFieldEnvName:    "environment",
+}
+
+var envFieldOrder = []string{EnvFieldHost, EnvFieldPID, EnvFieldContainer, EnvFieldPod, EnvFieldNamespace, EnvFieldNode, EnvFieldAppVersion, EnvFieldEnvName}
+
+func init() {
+	RegisterEnricher("environment", EnricherFunc(enrichEnvironment))
+}
+
+// EnvFieldsFromEnv parses FLOGO_CUSTOMLOG_ENV_FIELDS into the set of enabled fields.
+func EnvFieldsFromEnv() (map[string]bool, error) {
+	return ParseEnvFields(os.Getenv(EnvKeyEnvFields))
+}
+
+// ParseEnvFields parses a comma-separated list of environment field names (case-insensitive) or "all".
+func ParseEnvFields(list string) (map[string]bool, error) {
+	fields := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if strings.EqualFold(name, "all") {
+			for _, f := range envFieldOrder {
+				fields[f] = true
+			}
+			continue
+		}
+		field := ""
+		for _, f := range envFieldOrder {
+			if strings.EqualFold(f, name) {
+				field = f
+			}
+		}
+		if field == "" {
+			return nil, fmt.Errorf("unknown environment field [%s] in %s, valid values=[all, %s]",
+				name, EnvKeyEnvFields, strings.Join(envFieldOrder, ", "))
+		}
+		fields[field] = true
+	}
+	return fields, nil
+}
+
+// EnvironmentEnricher adds host, process, container and Kubernetes fields to each record.
+// The values are collected once, when the enricher is created.
+type EnvironmentEnricher struct {
+	values map[string]interface{}
+}
+
+// NewEnvironmentEnricher collects the values of the enabled fields. Fields whose value
+// cannot be determined (e.g. no container ID outside a container) are omitted.
+func NewEnvironmentEnricher(fields map[string]bool) *EnvironmentEnricher {
+	values := make(map[string]interface{})
+	add := func(field string, v interface{}) {
+		if fields[field] && v != "" {
+			values[envFieldKeys[field]] = v
+		}
+	}
+	if fields[EnvFieldHost] {
+		host, _ := os.Hostname()
+		add(EnvFieldHost, host)
+	}
+	add(EnvFieldPID, os.Getpid())
+	if fields[EnvFieldContainer] {
+		add(EnvFieldContainer, containerID("/proc/self/cgroup", "/proc/self/mountinfo"))
+	}
+	add(EnvFieldPod, os.Getenv(EnvKeyPodName))
+	add(EnvFieldNamespace, os.Getenv(EnvKeyPodNamespace))
+	add(EnvFieldNode, os.Getenv(EnvKeyNodeName))
+	add(EnvFieldAppVersion, engine.GetAppVersion())
+	add(EnvFieldEnvName, engine.GetEnvName())
+	return &EnvironmentEnricher{values: values}
+}
+
+// Enrich adds the collected fields to r.
+func (e *EnvironmentEnricher) Enrich(r *Record) {
+	for k, v := range e.values {
+		r.Data[k] = v
+	}
+}
+
+var (
+	envEnricherOnce sync.Once
+	envEnricher     *EnvironmentEnricher
+)
+
+// enrichEnvironment is the registered enricher. It is created on first use, once the
+// engine has loaded the app, so that the app version and environment name are known.
+// An invalid FLOGO_CUSTOMLOG_ENV_FIELDS is rejected by ActivityConfig.Validate.
+func enrichEnvironment(r *Record) {
+	envEnricherOnce.Do(func() {
+		if fields, err := EnvFieldsFromEnv(); err == nil && len(fields) > 0 {
+			envEnricher = NewEnvironmentEnricher(fields)
+		}
+	})
+	if envEnricher != nil {
+		envEnricher.Enrich(r)
+	}
+}
+
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// containerID finds the container ID in the cgroup paths of the process (cgroup v1 and
+// systemd/containerd v2 layouts), falling back to the container directories in mountinfo
+// for cgroup v2 namespaces where /proc/self/cgroup only shows "0::/".
+func containerID(cgroupPath, mountinfoPath string) string {
+	if id := scanContainerID(cgroupPath, ""); id != "" {
+		return id
+	}
+	return scanContainerID(mountinfoPath, "/containers/")
+}
+
+func scanContainerID(path, marker string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if marker != "" {
+			i := strings.Index(line, marker)
+			if i < 0 {
+				continue
+			}
+			line = line[i+len(marker):]
+		}
+		if id := containerIDPattern.FindString(line); id != "" {
+			return id
+		}
+	}
+	return ""
+}
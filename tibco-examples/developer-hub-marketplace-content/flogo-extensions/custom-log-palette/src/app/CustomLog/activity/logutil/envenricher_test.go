@@ -0,0 +1,67 @@
+package logutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testContainerID = "3f1c2a9b8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a"
+
+func TestParseEnvFields(t *testing.T) {
+	fields, err := ParseEnvFields(" Host, pid ,POD")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{EnvFieldHost: true, EnvFieldPID: true, EnvFieldPod: true}, fields)
+
+	fields, err = ParseEnvFields("all")
+	require.NoError(t, err)
+	assert.Len(t, fields, len(envFieldOrder))
+
+	fields, err = ParseEnvFields("")
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	_, err = ParseEnvFields("host,region")
+	assert.Error(t, err)
+}
+
+func TestEnvironmentEnricher(t *testing.T) {
+	t.Setenv(EnvKeyPodName, "orders-7d9f")
+	t.Setenv(EnvKeyPodNamespace, "payments")
+	t.Setenv(EnvKeyNodeName, "node-3")
+
+	e := NewEnvironmentEnricher(map[string]bool{EnvFieldPID: true, EnvFieldPod: true, EnvFieldNamespace: true})
+	r := &Record{Entry: Entry{Data: map[string]interface{}{}}}
+	e.Enrich(r)
+	assert.Equal(t, map[string]interface{}{"pid": os.Getpid(), "podName": "orders-7d9f", "namespace": "payments"}, r.Data)
+
+	host, _ := os.Hostname()
+	r = &Record{Entry: Entry{Data: map[string]interface{}{}}}
+	NewEnvironmentEnricher(map[string]bool{EnvFieldHost: true, EnvFieldNode: true}).Enrich(r)
+	assert.Equal(t, map[string]interface{}{"hostName": host, "nodeName": "node-3"}, r.Data)
+}
+
+func TestContainerID(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	v1 := write("cgroup-v1", "12:pids:/kubepods/besteffort/pod1234/"+testContainerID+"\n")
+	systemd := write("cgroup-systemd", "0::/system.slice/cri-containerd-"+testContainerID+".scope\n")
+	v2 := write("cgroup-v2", "0::/\n")
+	mountinfo := write("mountinfo", "1 2 0:3 / / rw - overlay overlay rw\n"+
+		"3 1 8:1 /var/lib/docker/containers/"+testContainerID+"/hostname /etc/hostname rw - ext4 /dev/sda1 rw\n")
+	none := write("mountinfo-none", "1 2 0:3 / / rw - overlay overlay rw\n")
+
+	assert.Equal(t, testContainerID, containerID(v1, none))
+	assert.Equal(t, testContainerID, containerID(systemd, none))
+	assert.Equal(t, testContainerID, containerID(v2, mountinfo))
+	assert.Equal(t, "", containerID(v2, none))
+	assert.Equal(t, "", containerID(filepath.Join(dir, "missing"), none))
+}
@@ -0,0 +1,161 @@
+package logutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCaseKeys are tried in order for the case ID of a process-mining event.
+var DefaultCaseKeys = []string{"processInstanceId", "correlationId"}
+
+// eventLogSkipped are keys that become the case, activity or timestamp, or only select the output.
+var eventLogSkipped = map[string]bool{"activityName": true, "timeStamp": true, "logFormat": true}
+
+// EventLogConfig maps log records onto a process-mining event log.
+type EventLogConfig struct {
+	// CaseKeys are tried in order for the case ID (default: processInstanceId, correlationId).
+	CaseKeys []string
+	// ObjectKeys are context keys (e.g. orderId, customerId) whose values become OCEL objects
+	// typed by the key name. The case key is always an object type.
+	ObjectKeys []string
+}
+
+// MiningEvent is one event of an EventLog.
+type MiningEvent struct {
+	ID       string
+	CaseKey  string
+	CaseID   string
+	Activity string
+	Time     time.Time
+	// Attributes are the remaining log data keys (contextParams, message, level, ...).
+	Attributes map[string]interface{}
+}
+
+// EventLog collects process-mining events in memory and writes them as XES or OCEL 2.0.
+// It is safe for concurrent use.
+type EventLog struct {
+	cfg EventLogConfig
+
+	mu     sync.Mutex
+	events []MiningEvent
+}
+
+// NewEventLog returns an empty event log.
+func NewEventLog(cfg EventLogConfig) *EventLog {
+	if len(cfg.CaseKeys) == 0 {
+		cfg.CaseKeys = DefaultCaseKeys
+	}
+	return &EventLog{cfg: cfg}
+}
+
+// Add maps a log data record onto an event. The event time is the record's timeStamp,
+// else fallback. Records without a case ID or activity name are ignored and Add returns false.
+func (l *EventLog) Add(logData map[string]interface{}, fallback time.Time) bool {
+	ev := MiningEvent{Activity: toString(logData["activityName"]), Time: fallback}
+	for _, k := range l.cfg.CaseKeys {
+		if id := toString(logData[k]); id != "" {
+			ev.CaseKey, ev.CaseID = k, id
+			break
+		}
+	}
+	if ev.CaseID == "" || ev.Activity == "" {
+		return false
+	}
+	if ts, ok := defaultTimestampConfig().Parse(toString(logData["timeStamp"])); ok {
+		ev.Time = ts
+	}
+	ev.Attributes = make(map[string]interface{}, len(logData))
+	for k, v := range logData {
+		if eventLogSkipped[k] || k == ev.CaseKey || toString(v) == "" {
+			continue
+		}
+		ev.Attributes[k] = v
+	}
+	l.mu.Lock()
+	ev.ID = "e" + strconv.Itoa(len(l.events)+1)
+	l.events = append(l.events, ev)
+	l.mu.Unlock()
+	return true
+}
+
+// Len returns the number of events.
+func (l *EventLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.events)
+}
+
+// snapshot returns the events ordered by time (stable for equal times).
+func (l *EventLog) snapshot() []MiningEvent {
+	l.mu.Lock()
+	events := append([]MiningEvent(nil), l.events...)
+	l.mu.Unlock()
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events
+}
+
+// ConvertLogLines reads log lines written with logFormat=json (one object per line, keys
+// named by the FLOGO_CUSTOMLOG_KEY_* layout, "a_key" by default) and adds them to l. Lines that are not JSON objects, such as engine
+// messages mixed into stdout, are skipped. It returns the number of events added.
+func ConvertLogLines(r io.Reader, l *EventLog) (int, error) {
+	names := defaultKeyNames(nil)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	added := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			continue
+		}
+		logData := make(map[string]interface{}, len(obj))
+		addData := func(name string, v interface{}) {
+			if k, ok := names.dataKey(name); ok {
+				logData[k] = v
+			}
+		}
+		for name, v := range obj {
+			if section, ok := v.(map[string]interface{}); ok && names.layout.Sections && isSection(name) {
+				for name, v := range section {
+					addData(name, v)
+				}
+				continue
+			}
+			if name != "timestamp" && name != "level" && name != "logger" {
+				addData(name, v)
+			}
+		}
+		fallback, _ := defaultTimestampConfig().Parse(toString(obj["timestamp"]))
+		if l.Add(logData, fallback) {
+			added++
+		}
+	}
+	return added, scanner.Err()
+}
+
+// eventLogValueType returns the XES/OCEL type of a value: integer, float, boolean or string.
+func eventLogValueType(v interface{}) string {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case float32, float64:
+		return "float"
+	case bool:
+		return "boolean"
+	}
+	return "string"
+}
+
+// eventLogTime formats event times for XES and OCEL (ISO 8601 with milliseconds and offset).
+func eventLogTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.000Z07:00")
+}
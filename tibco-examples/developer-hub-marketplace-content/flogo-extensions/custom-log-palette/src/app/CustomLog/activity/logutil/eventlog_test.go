@@ -0,0 +1,197 @@
+package logutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func miningTestLog(t *testing.T) *EventLog {
+	l := NewEventLog(EventLogConfig{ObjectKeys: []string{"orderId"}})
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	records := []map[string]interface{}{
+		{"processInstanceId": "p1", "activityName": "Receive", "timeStamp": base.Format(time.RFC3339Nano), "orderId": "o1", "amount": 12.5, "level": "Info"},
+		{"correlationId": "c9", "activityName": "Receive", "timeStamp": base.Add(time.Second).Format(time.RFC3339Nano), "orderId": "o2"},
+		{"processInstanceId": "p1", "activityName": "Ship", "timeStamp": base.Add(2 * time.Second).Format(time.RFC3339Nano), "orderId": "o1", "amount": "n/a", "note": "a<b & \"c\""},
+		{"activityName": "NoCase"},
+		{"processInstanceId": "p2"},
+	}
+	for i, r := range records {
+		assert.Equal(t, i < 3, l.Add(r, base), "record %d", i)
+	}
+	return l
+}
+
+func TestEventLogXES(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, miningTestLog(t).WriteXES(&buf))
+
+	var doc struct {
+		Traces []struct {
+			Strings []struct {
+				Key   string `xml:"key,attr"`
+				Value string `xml:"value,attr"`
+			} `xml:"string"`
+			Events []struct {
+				Strings []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:"value,attr"`
+				} `xml:"string"`
+				Dates []struct {
+					Value string `xml:"value,attr"`
+				} `xml:"date"`
+				Floats []struct {
+					Key string `xml:"key,attr"`
+				} `xml:"float"`
+			} `xml:"event"`
+		} `xml:"trace"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc), buf.String())
+	assert.Contains(t, buf.String(), `xes.version="1849-2016"`)
+	require.Len(t, doc.Traces, 2)
+	assert.Equal(t, "p1", doc.Traces[0].Strings[0].Value)
+	assert.Equal(t, "c9", doc.Traces[1].Strings[0].Value)
+
+	events := doc.Traces[0].Events
+	require.Len(t, events, 2)
+	assert.Equal(t, "concept:name", events[0].Strings[0].Key)
+	assert.Equal(t, "Receive", events[0].Strings[0].Value)
+	assert.Equal(t, "Ship", events[1].Strings[0].Value)
+	_, err := time.Parse(time.RFC3339, events[0].Dates[0].Value)
+	assert.NoError(t, err)
+	require.Len(t, events[0].Floats, 1)
+	assert.Equal(t, "amount", events[0].Floats[0].Key)
+	assert.Contains(t, buf.String(), `value="a&lt;b &amp; &#34;c&#34;"`)
+}
+
+func TestEventLogOCEL(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, miningTestLog(t).WriteOCEL(&buf))
+
+	var doc ocelDocument
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	names := func(types []ocelType) []string {
+		var out []string
+		for _, t := range types {
+			out = append(out, t.Name)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"correlationId", "orderId", "processInstanceId"}, names(doc.ObjectTypes))
+	assert.Equal(t, []string{"Receive", "Ship"}, names(doc.EventTypes))
+	assert.Len(t, doc.Objects, 4)
+
+	require.Len(t, doc.Events, 3)
+	first := doc.Events[0]
+	assert.Equal(t, "e1", first.ID)
+	assert.Equal(t, "Receive", first.Type)
+	assert.Equal(t, []ocelRelationship{
+		{ObjectID: "processInstanceId:p1", Qualifier: "processInstanceId"},
+		{ObjectID: "orderId:o1", Qualifier: "orderId"},
+	}, first.Relationships)
+	for _, a := range first.Attributes {
+		assert.NotEqual(t, "orderId", a.Name)
+	}
+	assert.Equal(t, "correlationId:c9", doc.Events[1].Relationships[0].ObjectID)
+
+	// "amount" is a float on Receive and a string on Ship; each event type declares its own type
+	assert.Contains(t, doc.EventTypes[0].Attributes, ocelAttribute{Name: "amount", Type: "float"})
+	assert.Contains(t, doc.EventTypes[1].Attributes, ocelAttribute{Name: "amount", Type: "string"})
+}
+
+func TestConvertLogLines(t *testing.T) {
+	var lines strings.Builder
+	for i, activity := range []string{"Receive", "Ship"} {
+		data := map[string]interface{}{
+			"processInstanceId": "p1",
+			"activityName":      activity,
+			"message":           "step",
+			"timeStamp":         time.Date(2026, 3, 1, 10, 0, i, 0, time.Local).Format(time.RFC3339Nano),
+		}
+		lines.WriteString(formatJSON(data, "INFO", "flogo.test", time.Now(), JSONConfig{}, prefixKeys))
+		lines.WriteString("\n2026-03-01 INFO engine started\n")
+	}
+	lines.WriteString("{not json\n")
+
+	l := NewEventLog(EventLogConfig{})
+	n, err := ConvertLogLines(strings.NewReader(lines.String()), l)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	events := l.snapshot()
+	assert.Equal(t, "p1", events[0].CaseID)
+	assert.Equal(t, "Ship", events[1].Activity)
+	assert.Equal(t, "step", events[1].Attributes["message"])
+}
+
+func TestEventLogSink(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewEventLogSink(EventLogSinkConfig{Format: "csv", Path: filepath.Join(dir, "x")})
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, "mining", "events.jsonocel")
+	s, err := NewEventLogSink(EventLogSinkConfig{Format: EventLogOCEL, Path: path})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(&Entry{Data: map[string]interface{}{"processInstanceId": "p1", "activityName": "Receive"}}))
+	require.NoError(t, s.Write(&Entry{Data: map[string]interface{}{"message": "not an event"}}))
+	require.NoError(t, s.Close())
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var doc ocelDocument
+	require.NoError(t, json.Unmarshal(raw, &doc))
+	assert.Len(t, doc.Events, 1)
+	leftovers, _ := filepath.Glob(path + ".*.tmp")
+	assert.Empty(t, leftovers)
+}
+
+func TestEventLogSinkMaxEvents(t *testing.T) {
+	s, err := NewEventLogSink(EventLogSinkConfig{Format: EventLogXES, Path: filepath.Join(t.TempDir(), "events.xes"), MaxEvents: 2})
+	require.NoError(t, err)
+	defer s.Close()
+	event := &Entry{Data: map[string]interface{}{"processInstanceId": "p1", "activityName": "Receive"}}
+	require.NoError(t, s.Write(event))
+	// entries that are not events do not count
+	require.NoError(t, s.Write(&Entry{Data: map[string]interface{}{"message": "not an event"}}))
+	require.NoError(t, s.Write(event))
+	// drops are counted, not returned to every activity
+	assert.NoError(t, s.Write(event))
+	assert.NoError(t, s.Write(event))
+	assert.Equal(t, 2, s.log.Len())
+	assert.Equal(t, int64(2), s.Dropped())
+}
+
+func TestEventLogSinkConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyXESPath, "/tmp/events.xes")
+	t.Setenv(EnvKeyEventLogCaseKeys, "orderId, correlationId")
+	t.Setenv(EnvKeyEventLogFlushInterval, "5s")
+	cfg, err := EventLogSinkConfigFromEnv(EventLogXES)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orderId", "correlationId"}, cfg.EventLog.CaseKeys)
+	assert.Equal(t, 5*time.Second, cfg.FlushInterval)
+	assert.Equal(t, DefaultEventLogMaxEvents, cfg.MaxEvents)
+
+	_, err = EventLogSinkConfigFromEnv(EventLogOCEL)
+	assert.Error(t, err)
+	t.Setenv(EnvKeyEventLogFlushInterval, "soon")
+	_, err = EventLogSinkConfigFromEnv(EventLogXES)
+	assert.Error(t, err)
+
+	t.Setenv(EnvKeyEventLogFlushInterval, "")
+	t.Setenv(EnvKeyEventLogMaxEvents, "0")
+	cfg, err = EventLogSinkConfigFromEnv(EventLogXES)
+	require.NoError(t, err)
+	assert.Zero(t, cfg.MaxEvents)
+	t.Setenv(EnvKeyEventLogMaxEvents, "-1")
+	_, err = EventLogSinkConfigFromEnv(EventLogXES)
+	assert.Error(t, err)
+}
@@ -0,0 +1,228 @@
+package logutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/project-flogo/core/support/log"
+)
+
+// Environment variables configuring the "xes" and "ocel" sinks.
+const (
+	EnvKeyXESPath               = "FLOGO_CUSTOMLOG_XES_PATH"
+	EnvKeyOCELPath              = "FLOGO_CUSTOMLOG_OCEL_PATH"
+	EnvKeyEventLogCaseKeys      = "FLOGO_CUSTOMLOG_EVENTLOG_CASE_KEYS"
+	EnvKeyEventLogObjectKeys    = "FLOGO_CUSTOMLOG_EVENTLOG_OBJECT_KEYS"
+	EnvKeyEventLogFlushInterval = "FLOGO_CUSTOMLOG_EVENTLOG_FLUSH_INTERVAL"
+	EnvKeyEventLogMaxEvents     = "FLOGO_CUSTOMLOG_EVENTLOG_MAX_EVENTS"
+)
+
+// DefaultEventLogFlushInterval is how often the event log file is rewritten.
+const DefaultEventLogFlushInterval = 30 * time.Second
+
+// DefaultEventLogMaxEvents bounds the memory (and file size) of an event log sink.
+const DefaultEventLogMaxEvents = 100000
+
+// Event log file formats.
+const (
+	EventLogXES  = "xes"
+	EventLogOCEL = "ocel"
+)
+
+func init() {
+	for _, format := range []string{EventLogXES, EventLogOCEL} {
+		RegisterSink(format, func() (Sink, error) {
+			cfg, err := EventLogSinkConfigFromEnv(format)
+			if err != nil {
+				return nil, err
+			}
+			return NewEventLogSink(cfg)
+		})
+	}
+}
+
+// EventLogSinkConfig configures an EventLogSink.
+type EventLogSinkConfig struct {
+	// Format is EventLogXES or EventLogOCEL.
+	Format string
+	// Path of the event log file. It is rewritten as a whole on every flush.
+	Path string
+	// EventLog maps records onto cases, activities and objects.
+	EventLog EventLogConfig
+	// FlushInterval rewrites the file periodically (0 = only on Flush and Close).
+	FlushInterval time.Duration
+	// MaxEvents caps the events kept in memory (0 = unlimited). Later events are dropped and counted.
+	MaxEvents int
+}
+
+// EventLogSinkConfigFromEnv reads the configuration of the "xes" or "ocel" sink from
+// FLOGO_CUSTOMLOG_{XES,OCEL}_PATH and the shared FLOGO_CUSTOMLOG_EVENTLOG_* variables.
+func EventLogSinkConfigFromEnv(format string) (EventLogSinkConfig, error) {
+	pathKey := EnvKeyXESPath
+	if format == EventLogOCEL {
+		pathKey = EnvKeyOCELPath
+	}
+	cfg := EventLogSinkConfig{
+		Format:        format,
+		Path:          os.Getenv(pathKey),
+		FlushInterval: DefaultEventLogFlushInterval,
+		MaxEvents:     DefaultEventLogMaxEvents,
+		EventLog: EventLogConfig{
+			CaseKeys:   splitList(os.Getenv(EnvKeyEventLogCaseKeys)),
+			ObjectKeys: splitList(os.Getenv(EnvKeyEventLogObjectKeys)),
+		},
+	}
+	if cfg.Path == "" {
+		return cfg, fmt.Errorf("%s is required for the %s sink", pathKey, format)
+	}
+	if v := os.Getenv(EnvKeyEventLogFlushInterval); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyEventLogFlushInterval, v)
+		}
+		cfg.FlushInterval = d
+	}
+	if v := os.Getenv(EnvKeyEventLogMaxEvents); v != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyEventLogMaxEvents, v)
+		}
+		cfg.MaxEvents = n
+	}
+	return cfg, nil
+}
+
+// EventLogSink collects entries into an EventLog and writes it as an XES or OCEL 2.0 file.
+// The log is kept in memory up to MaxEvents, so it suits bounded runs (tests, batch jobs,
+// process discovery sessions); for long-running engines, write logFormat=json lines to a
+// file and convert them offline with ConvertLogLines.
+type EventLogSink struct {
+	cfg EventLogSinkConfig
+	log *EventLog
+
+	writeMu  sync.Mutex
+	dropped  atomic.Int64
+	dropWarn warnLimiter
+
+	flushMu sync.Mutex
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewEventLogSink validates cfg and returns a sink. The file is first written on flush.
+func NewEventLogSink(cfg EventLogSinkConfig) (*EventLogSink, error) {
+	switch cfg.Format {
+	case EventLogXES, EventLogOCEL:
+	default:
+		return nil, fmt.Errorf("invalid event log format [%s], valid values=[xes, ocel]", cfg.Format)
+	}
+	if cfg.Path == "" {
+		return nil, errors.New("event log path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, err
+	}
+	s := &EventLogSink{
+		cfg:  cfg,
+		log:  NewEventLog(cfg.EventLog),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if cfg.FlushInterval > 0 {
+		go s.flushRun()
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+// Write adds the entry to the event log. Entries without a case ID or activity name are skipped;
+// once MaxEvents is reached, entries are dropped and counted (see Dropped), with an engine
+// warning at most once per minute.
+func (s *EventLogSink) Write(e *Entry) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if s.cfg.MaxEvents > 0 && s.log.Len() >= s.cfg.MaxEvents {
+		s.dropped.Add(1)
+		if n := s.dropWarn.add(1); n > 0 {
+			log.RootLogger().Warnf("CustomLog %s event log is full (%d events), %d records dropped since the last warning", s.cfg.Format, s.cfg.MaxEvents, n)
+		}
+		return nil
+	}
+	s.log.Add(e.Data, e.timeOr(time.Now))
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the event log was full.
+func (s *EventLogSink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Flush rewrites the file atomically (temporary file, then rename).
+func (s *EventLogSink) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	tmp, err := os.CreateTemp(filepath.Dir(s.cfg.Path), filepath.Base(s.cfg.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if s.cfg.Format == EventLogOCEL {
+		err = s.log.WriteOCEL(tmp)
+	} else {
+		err = s.log.WriteXES(tmp)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.cfg.Path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Close stops the periodic flush and writes the file a last time.
+func (s *EventLogSink) Close() error {
+	s.once.Do(func() {
+		if s.cfg.FlushInterval > 0 {
+			close(s.stop)
+		}
+	})
+	<-s.done
+	return s.Flush()
+}
+
+func (s *EventLogSink) flushRun() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,416 @@
+package logutil
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables configuring the "file" sink.
+const (
+	EnvKeyFilePath       = "FLOGO_CUSTOMLOG_FILE_PATH"
+	EnvKeyFileMaxSizeMB  = "FLOGO_CUSTOMLOG_FILE_MAX_SIZE_MB"
+	EnvKeyFileRotate     = "FLOGO_CUSTOMLOG_FILE_ROTATE"
+	EnvKeyFileMaxBackups = "FLOGO_CUSTOMLOG_FILE_MAX_BACKUPS"
+	EnvKeyFileMaxAgeDays = "FLOGO_CUSTOMLOG_FILE_MAX_AGE_DAYS"
+	EnvKeyFileCompress   = "FLOGO_CUSTOMLOG_FILE_COMPRESS"
+)
+
+// Time based rotation periods for FileSinkConfig.Rotate.
+const (
+	RotateNone   = ""
+	RotateDaily  = "daily"
+	RotateHourly = "hourly"
+)
+
+// backupTimeFormat is embedded in rotated file names: <name>-<time><ext>[.gz]
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+func init() {
+	RegisterSink("file", func() (Sink, error) {
+		cfg, err := FileSinkConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewFileSink(cfg)
+	})
+}
+
+// FileSinkConfig configures a rotating FileSink.
+type FileSinkConfig struct {
+	// Path of the active log file. Rotated files are written next to it.
+	Path string
+	// MaxSizeBytes rotates the file before a write would exceed it (0 = no size rotation).
+	MaxSizeBytes int64
+	// Rotate is RotateNone, RotateDaily or RotateHourly.
+	Rotate string
+	// MaxBackups is the number of rotated files to keep (0 = keep all).
+	MaxBackups int
+	// MaxAge removes rotated files older than this (0 = no age limit).
+	MaxAge time.Duration
+	// Compress gzips rotated files.
+	Compress bool
+	// LocalTime uses local time for rotation boundaries and backup names instead of UTC.
+	LocalTime bool
+}
+
+// FileSinkConfigFromEnv reads the file sink configuration from FLOGO_CUSTOMLOG_FILE_* variables.
+func FileSinkConfigFromEnv() (FileSinkConfig, error) {
+	cfg := FileSinkConfig{
+		Path:   os.Getenv(EnvKeyFilePath),
+		Rotate: strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeyFileRotate))),
+	}
+	if cfg.Path == "" {
+		return cfg, fmt.Errorf("%s is required for the file sink", EnvKeyFilePath)
+	}
+	if v := os.Getenv(EnvKeyFileMaxSizeMB); v != "" {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || mb < 0 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyFileMaxSizeMB, v)
+		}
+		cfg.MaxSizeBytes = mb * 1024 * 1024
+	}
+	if v := os.Getenv(EnvKeyFileMaxBackups); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyFileMaxBackups, v)
+		}
+		cfg.MaxBackups = n
+	}
+	if v := os.Getenv(EnvKeyFileMaxAgeDays); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyFileMaxAgeDays, v)
+		}
+		cfg.MaxAge = time.Duration(n) * 24 * time.Hour
+	}
+	if v := os.Getenv(EnvKeyFileCompress); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyFileCompress, v)
+		}
+		cfg.Compress = b
+	}
+	return cfg, nil
+}
+
+// FileSink appends log lines to a file, rotating it by size and/or time.
+// Rotated files are compressed and pruned in a background goroutine so writers never wait on gzip.
+type FileSink struct {
+	cfg FileSinkConfig
+	now func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time
+
+	millCh   chan struct{}
+	millDone chan struct{}
+	closed   bool
+}
+
+// NewFileSink opens (or creates) cfg.Path for appending.
+func NewFileSink(cfg FileSinkConfig) (*FileSink, error) {
+	return newFileSink(cfg, time.Now)
+}
+
+func newFileSink(cfg FileSinkConfig, now func() time.Time) (*FileSink, error) {
+	switch cfg.Rotate {
+	case RotateNone, RotateDaily, RotateHourly:
+	default:
+		return nil, fmt.Errorf("invalid file rotation [%s], valid values=[daily, hourly]", cfg.Rotate)
+	}
+	if cfg.Path == "" {
+		return nil, errors.New("file sink path is empty")
+	}
+	s := &FileSink{
+		cfg:      cfg,
+		now:      now,
+		millCh:   make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	if err := s.openExisting(); err != nil {
+		return nil, err
+	}
+	go s.millRun()
+	return s, nil
+}
+
+func (s *FileSink) Write(e *Entry) error {
+	line := e.Line + "\n"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("file sink is closed")
+	}
+	now := s.timeNow()
+	var rotateErr error
+	if s.needsRotation(now, int64(len(line))) {
+		// a failed rotation keeps the current file, so the line is still written
+		rotateErr = s.rotate(now)
+	}
+	n, err := s.file.WriteString(line)
+	s.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return err
+}
+
+// Close flushes pending background work and closes the active file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	err := s.file.Close()
+	s.mu.Unlock()
+	close(s.millCh)
+	<-s.millDone
+	return err
+}
+
+// Rotate forces a rotation of the active file.
+func (s *FileSink) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("file sink is closed")
+	}
+	return s.rotate(s.timeNow())
+}
+
+func (s *FileSink) timeNow() time.Time {
+	if s.cfg.LocalTime {
+		return s.now()
+	}
+	return s.now().UTC()
+}
+
+func (s *FileSink) periodStart(t time.Time) time.Time {
+	switch s.cfg.Rotate {
+	case RotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case RotateDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+func (s *FileSink) needsRotation(now time.Time, n int64) bool {
+	if s.cfg.MaxSizeBytes > 0 && s.size > 0 && s.size+n > s.cfg.MaxSizeBytes {
+		return true
+	}
+	return s.cfg.Rotate != RotateNone && !s.periodStart(now).Equal(s.period)
+}
+
+func (s *FileSink) openExisting() error {
+	if err := os.MkdirAll(filepath.Dir(s.cfg.Path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file = f
+	s.size = info.Size()
+	// An existing file belongs to the period it was last written in, so a restart
+	// on the next day still rotates yesterday's file.
+	modTime := info.ModTime()
+	if !s.cfg.LocalTime {
+		modTime = modTime.UTC()
+	}
+	if s.size == 0 {
+		modTime = s.timeNow()
+	}
+	s.period = s.periodStart(modTime)
+	return nil
+}
+
+// rotate renames the active file to a timestamped backup and opens a fresh one. The old
+// file is closed only once the new one is open, so on error the sink keeps writing to it.
+// Caller holds mu.
+func (s *FileSink) rotate(now time.Time) error {
+	if s.size > 0 {
+		// a file removed externally (e.g. by logrotate) needs no backup
+		if err := os.Rename(s.cfg.Path, s.backupName(now)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_ = s.file.Close()
+	s.file = f
+	s.size = 0
+	s.period = s.periodStart(now)
+	select {
+	case s.millCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *FileSink) backupName(t time.Time) string {
+	dir, prefix, ext := s.nameParts()
+	name := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+	// Two rotations within the same millisecond must not overwrite each other.
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			if _, err := os.Stat(name + ".gz"); os.IsNotExist(err) {
+				return name
+			}
+		}
+		name = filepath.Join(dir, fmt.Sprintf("%s%s.%d%s", prefix, t.Format(backupTimeFormat), i, ext))
+	}
+}
+
+func (s *FileSink) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(s.cfg.Path)
+	base := filepath.Base(s.cfg.Path)
+	ext = filepath.Ext(base)
+	prefix = strings.TrimSuffix(base, ext) + "-"
+	return dir, prefix, ext
+}
+
+func (s *FileSink) millRun() {
+	defer close(s.millDone)
+	for range s.millCh {
+		_ = s.millRunOnce()
+	}
+}
+
+type backupFile struct {
+	path string
+	t    time.Time
+}
+
+// millRunOnce compresses and prunes rotated files.
+func (s *FileSink) millRunOnce() error {
+	if !s.cfg.Compress && s.cfg.MaxBackups == 0 && s.cfg.MaxAge == 0 {
+		return nil
+	}
+	backups, err := s.listBackups()
+	if err != nil {
+		return err
+	}
+	var remove []backupFile
+	keep := backups
+	if s.cfg.MaxBackups > 0 && len(keep) > s.cfg.MaxBackups {
+		remove = append(remove, keep[s.cfg.MaxBackups:]...)
+		keep = keep[:s.cfg.MaxBackups]
+	}
+	if s.cfg.MaxAge > 0 {
+		cutoff := s.timeNow().Add(-s.cfg.MaxAge)
+		var young []backupFile
+		for _, b := range keep {
+			if b.t.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				young = append(young, b)
+			}
+		}
+		keep = young
+	}
+	var firstErr error
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if s.cfg.Compress {
+		for _, b := range keep {
+			if strings.HasSuffix(b.path, ".gz") {
+				continue
+			}
+			if err := gzipFile(b.path); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// listBackups returns rotated files, newest first.
+func (s *FileSink) listBackups() ([]backupFile, error) {
+	dir, prefix, ext := s.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if s.cfg.LocalTime {
+		loc = time.Local
+	}
+	var backups []backupFile
+	for _, de := range entries {
+		name := de.Name()
+		if de.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(ts, ".gz")
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		ts = strings.TrimSuffix(ts, ext)
+		if len(ts) < len(backupTimeFormat) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, ts[:len(backupTimeFormat)], loc)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), t: t})
+	}
+	sort.SliceStable(backups, func(i, j int) bool {
+		if backups[i].t.Equal(backups[j].t) {
+			return backups[i].path > backups[j].path
+		}
+		return backups[i].t.After(backups[j].t)
+	})
+	return backups, nil
+}
+
+func gzipFile(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	dst := src + ".gz"
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err == nil {
+		err = gz.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
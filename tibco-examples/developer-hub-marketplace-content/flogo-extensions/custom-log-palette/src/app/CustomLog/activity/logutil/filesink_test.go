@@ -0,0 +1,136 @@
+package logutil
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSinkSizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	s, err := NewFileSink(FileSinkConfig{Path: path, MaxSizeBytes: 20, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, s.Write(&Entry{Line: fmt.Sprintf("line-%d-xxxxxxx", i)}))
+	}
+	require.NoError(t, s.Close())
+
+	active, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "line-5-xxxxxxx\n", string(active))
+
+	backups, err := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	require.NoError(t, err)
+	assert.Len(t, backups, 2)
+	plain, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	assert.Empty(t, plain)
+
+	f, err := os.Open(backups[len(backups)-1])
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "line-"))
+}
+
+func TestFileSinkTimeRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+	s, err := newFileSink(FileSinkConfig{Path: path, Rotate: RotateHourly}, func() time.Time { return now })
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(&Entry{Line: "a"}))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, s.Write(&Entry{Line: "b"}))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, s.Write(&Entry{Line: "c"}))
+	require.NoError(t, s.Close())
+
+	active, _ := os.ReadFile(path)
+	assert.Equal(t, "c\n", string(active))
+	rotated, err := os.ReadFile(filepath.Join(dir, "app-2026-03-01T11-15-00.000.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(rotated))
+}
+
+func TestFileSinkRotateFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "app.log")
+	s, err := NewFileSink(FileSinkConfig{Path: path, MaxSizeBytes: 4})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Write(&Entry{Line: "a"}))
+
+	// the new file cannot be created: the sink keeps its current file
+	require.NoError(t, os.RemoveAll(dir))
+	assert.Error(t, s.Rotate())
+	assert.Error(t, s.Write(&Entry{Line: "bcd"}))
+	_, err = s.file.Stat()
+	assert.NoError(t, err, "current file is still open")
+
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, s.Write(&Entry{Line: "e"}))
+	active, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "e\n", string(active))
+}
+
+func TestFileSinkConcurrentAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	s, err := NewFileSink(FileSinkConfig{Path: path})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_ = s.Write(&Entry{Line: fmt.Sprintf("g%d-%d", g, i)})
+			}
+		}(g)
+	}
+	wg.Wait()
+	require.NoError(t, s.Close())
+
+	content, _ := os.ReadFile(path)
+	assert.Len(t, strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"), 800)
+}
+
+func TestFileSinkConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyFilePath, "/var/log/flogo/app.log")
+	t.Setenv(EnvKeyFileMaxSizeMB, "10")
+	t.Setenv(EnvKeyFileRotate, "Daily")
+	t.Setenv(EnvKeyFileMaxBackups, "5")
+	t.Setenv(EnvKeyFileMaxAgeDays, "7")
+	t.Setenv(EnvKeyFileCompress, "true")
+
+	cfg, err := FileSinkConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, FileSinkConfig{
+		Path:         "/var/log/flogo/app.log",
+		MaxSizeBytes: 10 * 1024 * 1024,
+		Rotate:       RotateDaily,
+		MaxBackups:   5,
+		MaxAge:       7 * 24 * time.Hour,
+		Compress:     true,
+	}, cfg)
+
+	t.Setenv(EnvKeyFileMaxSizeMB, "ten")
+	_, err = FileSinkConfigFromEnv()
+	assert.Error(t, err)
+}
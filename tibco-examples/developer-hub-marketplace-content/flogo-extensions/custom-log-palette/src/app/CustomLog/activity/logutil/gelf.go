@@ -0,0 +1,95 @@
+package logutil
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gelfSkipped are keys carried by the GELF standard fields (short_message, full_message,
+// level, timestamp, host) or that only select the output.
+var gelfSkipped = map[string]bool{
+	"message": true, "errorData": true, "level": true, "timeStamp": true, "hostName": true, "logFormat": true,
+}
+
+var (
+	gelfHostOnce sync.Once
+	gelfHost     string
+)
+
+// defaultGELFHost is the host field when the record has no hostName (see FLOGO_CUSTOMLOG_ENV_FIELDS).
+func defaultGELFHost() string {
+	gelfHostOnce.Do(func() {
+		gelfHost, _ = os.Hostname()
+		if gelfHost == "" {
+			gelfHost = "flogo"
+		}
+	})
+	return gelfHost
+}
+
+// formatGELF writes a GELF 1.1 message: short_message from message, full_message from
+// errorData, the numeric syslog severity as level, and every other key as an additional
+// "_" field. host is used unless the record carries hostName.
+func formatGELF(logData map[string]interface{}, levelUpper string, loggerName string, now time.Time, host string) string {
+	if h := toString(logData["hostName"]); h != "" {
+		host = h
+	}
+	shortMessage := toString(logData["message"])
+	if shortMessage == "" {
+		// short_message is mandatory and must not be empty
+		shortMessage = "-"
+	}
+	var b strings.Builder
+	b.Grow(1024)
+	b.WriteString(`{"version":"1.1","host":`)
+	writeJSONString(&b, host)
+	b.WriteString(`,"short_message":`)
+	writeJSONString(&b, shortMessage)
+	if full := toString(logData["errorData"]); full != "" {
+		b.WriteString(`,"full_message":`)
+		writeJSONString(&b, full)
+	}
+	b.WriteString(`,"timestamp":`)
+	b.WriteString(strconv.FormatFloat(float64(now.UnixMilli())/1000, 'f', 3, 64))
+	b.WriteString(`,"level":`)
+	b.WriteString(strconv.Itoa(SyslogSeverity(levelUpper)))
+	b.WriteString(`,"_logger":`)
+	writeJSONString(&b, loggerName)
+	for _, k := range OrderedKeys(logData) {
+		if gelfSkipped[k] {
+			continue
+		}
+		v := logData[k]
+		if toString(v) == "" {
+			continue
+		}
+		b.WriteByte(',')
+		writeJSONString(&b, gelfFieldName(k))
+		b.WriteByte(':')
+		writeTypedJSONValue(&b, v)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// gelfFieldName prefixes k with "_" and replaces characters outside [A-Za-z0-9_.-].
+// "_id" is reserved by Graylog, so the key "id" becomes "_id_".
+func gelfFieldName(k string) string {
+	if k == "id" {
+		return "_id_"
+	}
+	b := make([]byte, 0, len(k)+1)
+	b = append(b, '_')
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '.' || c == '-' {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+	return string(b)
+}
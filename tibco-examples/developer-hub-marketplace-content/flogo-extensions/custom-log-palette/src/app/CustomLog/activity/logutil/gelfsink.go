@@ -0,0 +1,269 @@
+package logutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables configuring the "gelf" sink.
+const (
+	EnvKeyGELFAddress     = "FLOGO_CUSTOMLOG_GELF_ADDRESS"
+	EnvKeyGELFNetwork     = "FLOGO_CUSTOMLOG_GELF_NETWORK"
+	EnvKeyGELFCompression = "FLOGO_CUSTOMLOG_GELF_COMPRESSION"
+	EnvKeyGELFChunkSize   = "FLOGO_CUSTOMLOG_GELF_CHUNK_SIZE"
+	EnvKeyGELFHostname    = "FLOGO_CUSTOMLOG_GELF_HOSTNAME"
+	// EnvKeyGELFWriteTimeout bounds each tcp write, e.g. "5s"; default DialTimeout.
+	EnvKeyGELFWriteTimeout = "FLOGO_CUSTOMLOG_GELF_WRITE_TIMEOUT"
+)
+
+// GELF payload compression (UDP only; Graylog does not accept compressed TCP input).
+const (
+	GELFCompressNone = "none"
+	GELFCompressGzip = "gzip"
+	GELFCompressZlib = "zlib"
+)
+
+const (
+	// DefaultGELFChunkSize keeps each UDP datagram within a typical 1500 byte MTU.
+	DefaultGELFChunkSize = 1420
+	gelfChunkHeaderSize  = 12
+	gelfMaxChunks        = 128
+)
+
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+func init() {
+	RegisterSink("gelf", func() (Sink, error) {
+		cfg, err := GELFSinkConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewGELFSink(cfg)
+	})
+}
+
+// GELFSinkConfig configures a GELFSink.
+type GELFSinkConfig struct {
+	// Network is "udp" or "tcp".
+	Network string
+	// Address is the Graylog input host:port.
+	Address string
+	// Compression is GELFCompressNone, GELFCompressGzip or GELFCompressZlib (UDP only).
+	Compression string
+	// ChunkSize is the largest UDP datagram, header included; larger messages are chunked.
+	ChunkSize int
+	// Hostname overrides os.Hostname() in the host field.
+	Hostname string
+	// DialTimeout bounds each (re)connect attempt.
+	DialTimeout time.Duration
+	// WriteTimeout bounds the writes of each message over tcp (default DialTimeout); a
+	// timeout fails the write like a broken connection.
+	WriteTimeout time.Duration
+}
+
+// GELFSinkConfigFromEnv reads the GELF sink configuration from FLOGO_CUSTOMLOG_GELF_* variables.
+func GELFSinkConfigFromEnv() (GELFSinkConfig, error) {
+	cfg := GELFSinkConfig{
+		Network:     strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeyGELFNetwork))),
+		Address:     os.Getenv(EnvKeyGELFAddress),
+		Compression: strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeyGELFCompression))),
+		Hostname:    os.Getenv(EnvKeyGELFHostname),
+	}
+	if cfg.Address == "" {
+		return cfg, fmt.Errorf("%s is required for the gelf sink", EnvKeyGELFAddress)
+	}
+	if v := os.Getenv(EnvKeyGELFChunkSize); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyGELFChunkSize, v)
+		}
+		cfg.ChunkSize = n
+	}
+	if v := os.Getenv(EnvKeyGELFWriteTimeout); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyGELFWriteTimeout, v)
+		}
+		cfg.WriteTimeout = d
+	}
+	return cfg, nil
+}
+
+// GELFSink sends entries to Graylog as GELF 1.1 messages. Over UDP, messages larger
+// than ChunkSize are split into GELF chunks; over TCP, messages are null-byte terminated
+// and a broken connection is re-established on the next write.
+type GELFSink struct {
+	cfg  GELFSinkConfig
+	host string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewGELFSink validates cfg and returns a sink. The connection is opened on first write.
+func NewGELFSink(cfg GELFSinkConfig) (*GELFSink, error) {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.Compression == "" {
+		cfg.Compression = GELFCompressNone
+	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = DefaultGELFChunkSize
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = cfg.DialTimeout
+	}
+	switch cfg.Network {
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("invalid gelf network [%s], valid values=[udp, tcp]", cfg.Network)
+	}
+	switch cfg.Compression {
+	case GELFCompressNone, GELFCompressGzip, GELFCompressZlib:
+	default:
+		return nil, fmt.Errorf("invalid gelf compression [%s], valid values=[none, gzip, zlib]", cfg.Compression)
+	}
+	if cfg.Network == "tcp" && cfg.Compression != GELFCompressNone {
+		return nil, errors.New("gelf compression is only supported over udp")
+	}
+	if cfg.ChunkSize <= gelfChunkHeaderSize || cfg.ChunkSize > 65507 {
+		return nil, fmt.Errorf("invalid gelf chunk size [%d]", cfg.ChunkSize)
+	}
+	if cfg.Address == "" {
+		return nil, errors.New("gelf address is empty")
+	}
+	host := cfg.Hostname
+	if host == "" {
+		host = defaultGELFHost()
+	}
+	return &GELFSink{cfg: cfg, host: host}, nil
+}
+
+func (s *GELFSink) Write(e *Entry) error {
+	msg := []byte(formatGELF(e.Data, strings.ToUpper(e.Level), e.LoggerName, e.timeOr(time.Now), s.host))
+	if s.cfg.Network == "tcp" {
+		msg = append(msg, 0)
+		return s.send([][]byte{msg})
+	}
+	msg, err := gelfCompress(msg, s.cfg.Compression)
+	if err != nil {
+		return err
+	}
+	datagrams, err := gelfChunks(msg, s.cfg.ChunkSize)
+	if err != nil {
+		return err
+	}
+	return s.send(datagrams)
+}
+
+func (s *GELFSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// send writes the packets on the current connection, with one retry on a fresh connection.
+func (s *GELFSink) send(packets [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = net.DialTimeout(s.cfg.Network, s.cfg.Address, s.cfg.DialTimeout); err != nil {
+				s.conn = nil
+				continue
+			}
+		}
+		if s.cfg.Network == "tcp" {
+			if err = s.conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout)); err != nil {
+				s.conn.Close()
+				s.conn = nil
+				continue
+			}
+		}
+		for _, p := range packets {
+			if _, err = s.conn.Write(p); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		// a timed-out write may have sent part of the message, so the connection is dropped too
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func gelfCompress(msg []byte, compression string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch compression {
+	case GELFCompressGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(msg); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case GELFCompressZlib:
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(msg); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return msg, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// gelfChunks splits msg into GELF chunks of at most chunkSize bytes. Each chunk starts with
+// the magic bytes 0x1e 0x0f, an 8 byte message ID, the sequence number and the chunk count.
+func gelfChunks(msg []byte, chunkSize int) ([][]byte, error) {
+	if len(msg) <= chunkSize {
+		return [][]byte{msg}, nil
+	}
+	payload := chunkSize - gelfChunkHeaderSize
+	count := (len(msg) + payload - 1) / payload
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("gelf message of %d bytes needs %d chunks, more than the maximum of %d", len(msg), count, gelfMaxChunks)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		part := msg[i*payload : min((i+1)*payload, len(msg))]
+		chunk := make([]byte, 0, gelfChunkHeaderSize+len(part))
+		chunk = append(chunk, gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, part...)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
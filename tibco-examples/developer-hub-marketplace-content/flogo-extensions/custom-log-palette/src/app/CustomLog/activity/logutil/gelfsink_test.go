@@ -0,0 +1,227 @@
+package logutil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gelfTestEntry(message string) *Entry {
+	return &Entry{
+		Time:       fixedTime(),
+		Level:      "ERROR",
+		LoggerName: "flogo.test",
+		Data: map[string]interface{}{
+			"applicationName": "Order App",
+			"correlationId":   "c-1",
+			"level":           "Error",
+			"message":         message,
+			"errorData":       "stack\ntrace",
+			"pid":             42,
+			"id":              "x",
+			"order id":        "o-9",
+		},
+	}
+}
+
+func TestFormatGELF(t *testing.T) {
+	e := gelfTestEntry("Payment failed")
+	line := formatGELF(e.Data, "ERROR", e.LoggerName, e.Time, "host1")
+
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &msg))
+	assert.Equal(t, map[string]interface{}{
+		"version":          "1.1",
+		"host":             "host1",
+		"short_message":    "Payment failed",
+		"full_message":     "stack\ntrace",
+		"timestamp":        1772360130.123,
+		"level":            float64(3),
+		"_logger":          "flogo.test",
+		"_applicationName": "Order App",
+		"_correlationId":   "c-1",
+		"_pid":             float64(42),
+		"_id_":             "x",
+		"_order_id":        "o-9",
+	}, msg)
+	assert.True(t, strings.HasPrefix(line, `{"version":"1.1","host":"host1","short_message":`), line)
+
+	e.Data["hostName"] = "pod-1"
+	delete(e.Data, "message")
+	require.NoError(t, json.Unmarshal([]byte(formatGELF(e.Data, "INFO", "l", e.Time, "host1")), &msg))
+	assert.Equal(t, "pod-1", msg["host"])
+	assert.Equal(t, "-", msg["short_message"])
+	assert.Equal(t, float64(6), msg["level"])
+}
+
+func TestGELFSinkUDPChunked(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	s, err := NewGELFSink(GELFSinkConfig{Address: pc.LocalAddr().String(), ChunkSize: 100, Hostname: "host1"})
+	require.NoError(t, err)
+	defer s.Close()
+	message := strings.Repeat("payment failed ", 40)
+	require.NoError(t, s.Write(gelfTestEntry(message)))
+
+	chunks := make(map[byte][]byte)
+	var id []byte
+	count := 0
+	buf := make([]byte, 2048)
+	for count == 0 || len(chunks) < count {
+		_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		require.LessOrEqual(t, n, 100)
+		require.Equal(t, []byte{0x1e, 0x0f}, buf[:2])
+		if id == nil {
+			id = append([]byte(nil), buf[2:10]...)
+		}
+		assert.Equal(t, id, buf[2:10])
+		count = int(buf[11])
+		chunks[buf[10]] = append([]byte(nil), buf[12:n]...)
+	}
+	var payload []byte
+	for i := 0; i < count; i++ {
+		payload = append(payload, chunks[byte(i)]...)
+	}
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &msg))
+	assert.Equal(t, message, msg["short_message"])
+}
+
+func TestGELFSinkUDPGzip(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	s, err := NewGELFSink(GELFSinkConfig{Address: pc.LocalAddr().String(), Compression: GELFCompressGzip})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Write(gelfTestEntry("Payment failed")))
+
+	buf := make([]byte, 4096)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	r, err := gzip.NewReader(bytes.NewReader(buf[:n]))
+	require.NoError(t, err)
+	payload, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), `"short_message":"Payment failed"`)
+}
+
+func TestGELFSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var msgs []string
+		for len(msgs) < 2 {
+			m, err := r.ReadString(0)
+			if err != nil {
+				break
+			}
+			msgs = append(msgs, strings.TrimSuffix(m, "\x00"))
+		}
+		received <- msgs
+	}()
+
+	s, err := NewGELFSink(GELFSinkConfig{Network: "tcp", Address: ln.Addr().String()})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Write(gelfTestEntry("first")))
+	require.NoError(t, s.Write(gelfTestEntry("second")))
+
+	select {
+	case msgs := <-received:
+		require.Len(t, msgs, 2)
+		assert.Contains(t, msgs[0], `"short_message":"first"`)
+		assert.Contains(t, msgs[1], `"short_message":"second"`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no GELF messages received")
+	}
+}
+
+func TestGELFSinkTCPWriteTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	// Graylog accepts connections but never reads
+	var conns []net.Conn
+	var mu sync.Mutex
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+
+	s, err := NewGELFSink(GELFSinkConfig{Network: "tcp", Address: ln.Addr().String(), WriteTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	defer s.Close()
+
+	start := time.Now()
+	err = s.Write(gelfTestEntry(strings.Repeat("x", 32<<20)))
+	require.Error(t, err)
+	var ne net.Error
+	assert.True(t, errors.As(err, &ne) && ne.Timeout(), err.Error())
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// the default is the dial timeout
+	d, err := NewGELFSink(GELFSinkConfig{Network: "tcp", Address: ln.Addr().String(), DialTimeout: 3 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, 3*time.Second, d.cfg.WriteTimeout)
+
+	t.Setenv(EnvKeyGELFAddress, "127.0.0.1:12201")
+	t.Setenv(EnvKeyGELFWriteTimeout, "2s")
+	cfg, err := GELFSinkConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, cfg.WriteTimeout)
+	t.Setenv(EnvKeyGELFWriteTimeout, "soon")
+	_, err = GELFSinkConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestNewGELFSinkInvalid(t *testing.T) {
+	_, err := NewGELFSink(GELFSinkConfig{Network: "tcp", Address: "127.0.0.1:12201", Compression: GELFCompressZlib})
+	assert.Error(t, err)
+	_, err = NewGELFSink(GELFSinkConfig{Address: "127.0.0.1:12201", Compression: "lz4"})
+	assert.Error(t, err)
+	_, err = NewGELFSink(GELFSinkConfig{Address: "127.0.0.1:12201", ChunkSize: 10})
+	assert.Error(t, err)
+
+	_, err = gelfChunks(make([]byte, 200*100), 100)
+	assert.Error(t, err, "more than 128 chunks")
+}
@@ -0,0 +1,138 @@
+package logutil
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+
+	"github.com/project-flogo/core/activity"
+)
+
+// Strategies generating the identifiers missing from a Set and Log Message context.
+const (
+	IDStrategyNone         = "none"
+	IDStrategyUUIDv4       = "uuidv4"
+	IDStrategyUUIDv7       = "uuidv7"
+	IDStrategyULID         = "ulid"
+	IDStrategyFlowInstance = "flowInstance"
+)
+
+var idStrategies = []string{IDStrategyNone, IDStrategyUUIDv4, IDStrategyUUIDv7, IDStrategyULID, IDStrategyFlowInstance}
+
+// GeneratedIDKeys are the context keys generated when absent.
+var GeneratedIDKeys = []string{"sessionId", "correlationId", "trackingId"}
+
+// GenerateID returns a new identifier for strategy. flowID is the flow instance ID used by
+// flowInstance, which returns it as is so every key of the instance gets the same value.
+// It returns "" for none or an unknown strategy.
+func GenerateID(strategy, flowID string) string {
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case IDStrategyUUIDv4:
+		return NewUUIDv4()
+	case IDStrategyUUIDv7:
+		return NewUUIDv7()
+	case IDStrategyULID:
+		return NewULID()
+	case strings.ToLower(IDStrategyFlowInstance):
+		return flowID
+	}
+	return ""
+}
+
+// FillMissingIDs sets the GeneratedIDKeys that are empty in info (the customFlowInfo being
+// stored) to the value already in the flow context, e.g. from an earlier Set and Log Message
+// or the root flow, else to a new ID of strategy. It returns the keys it set; with
+// strategy none (or empty) info is left unchanged.
+func FillMissingIDs(ctx activity.Context, info map[string]interface{}, strategy string) map[string]interface{} {
+	filled := make(map[string]interface{})
+	if s := strings.TrimSpace(strategy); s == "" || strings.EqualFold(s, IDStrategyNone) {
+		return filled
+	}
+	var current map[string]interface{}
+	for _, k := range GeneratedIDKeys {
+		if v := info[k]; v != nil && v != "" {
+			continue
+		}
+		if current == nil {
+			current = CustomFlowInfo(ctx)
+		}
+		v := current[k]
+		if v == nil || v == "" {
+			id := GenerateID(strategy, ctx.ActivityHost().ID())
+			if id == "" {
+				continue
+			}
+			v = id
+		}
+		info[k] = v
+		filled[k] = v
+	}
+	return filled
+}
+
+// NewUUIDv4 returns a random RFC 9562 version 4 UUID.
+func NewUUIDv4() string {
+	var u [16]byte
+	randomBytes(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u)
+}
+
+// NewUUIDv7 returns an RFC 9562 version 7 UUID: the Unix time in milliseconds (from Now)
+// followed by random bits, so IDs sort by creation time.
+func NewUUIDv7() string {
+	var u [16]byte
+	randomBytes(u[6:])
+	putMillis(u[:6])
+	u[6] = u[6]&0x0f | 0x70
+	u[8] = u[8]&0x3f | 0x80
+	return formatUUID(u)
+}
+
+// crockford is the Crockford base32 alphabet of ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a ULID: 48 bits of Unix time in milliseconds (from Now) and 80 random bits,
+// as 26 Crockford base32 characters.
+func NewULID() string {
+	var u [16]byte
+	randomBytes(u[6:])
+	putMillis(u[:6])
+	hi, lo := binary.BigEndian.Uint64(u[:8]), binary.BigEndian.Uint64(u[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+func putMillis(b []byte) {
+	ms := uint64(Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+func randomBytes(b []byte) {
+	// crypto/rand.Read never returns an error (Go 1.24)
+	_, _ = rand.Read(b)
+}
+
+func formatUUID(u [16]byte) string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
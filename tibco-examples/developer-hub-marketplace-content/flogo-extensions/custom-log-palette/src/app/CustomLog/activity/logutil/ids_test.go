@@ -0,0 +1,82 @@
+package logutil
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	uuidV4Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	uuidV7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidPattern   = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+)
+
+func TestGenerateID(t *testing.T) {
+	SetClock(fixedTime)
+	t.Cleanup(func() { SetClock(nil) })
+	ms := fixedTime().UnixMilli()
+
+	v4 := GenerateID("UUIDv4", "42")
+	assert.Regexp(t, uuidV4Pattern, v4)
+	assert.NotEqual(t, v4, NewUUIDv4())
+
+	v7 := GenerateID(IDStrategyUUIDv7, "42")
+	require.Regexp(t, uuidV7Pattern, v7)
+	// the first 48 bits are the Unix time in milliseconds
+	ts, err := strconv.ParseInt(strings.ReplaceAll(v7[:13], "-", ""), 16, 64)
+	require.NoError(t, err)
+	assert.Equal(t, ms, ts)
+
+	ulid := GenerateID(IDStrategyULID, "42")
+	require.Regexp(t, ulidPattern, ulid)
+	// the first 10 characters are the Unix time in milliseconds
+	var decoded int64
+	for _, c := range ulid[:10] {
+		decoded = decoded<<5 | int64(strings.IndexRune(crockford, c))
+	}
+	assert.Equal(t, ms, decoded)
+	assert.NotEqual(t, ulid, NewULID())
+
+	assert.Equal(t, "42", GenerateID("flowinstance", "42"))
+	assert.Empty(t, GenerateID(IDStrategyNone, "42"))
+}
+
+func TestValidateIDStrategy(t *testing.T) {
+	for _, s := range []string{"", "none", "UUIDv7", "ulid", "flowInstance"} {
+		assert.NoError(t, ValidateIDStrategy(s), s)
+	}
+	assertErrorCode(t, ValidateIDStrategy("uuidv1"), ErrCodeInvalidIDStrategy)
+	cfg := &ActivityConfig{IDStrategy: "snowflake"}
+	assertErrorCode(t, cfg.Validate(), ErrCodeInvalidIDStrategy)
+}
+
+func TestFillMissingIDs(t *testing.T) {
+	ctx := newRecordContext("fill-1")
+	t.Cleanup(func() { ReleaseFlowContext("fill-1") })
+
+	info := map[string]interface{}{"sessionId": "s-1", "correlationId": ""}
+	assert.Empty(t, FillMissingIDs(ctx, info, IDStrategyNone))
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-1", "correlationId": ""}, info)
+
+	filled := FillMissingIDs(ctx, info, IDStrategyUUIDv4)
+	assert.Len(t, filled, 2)
+	assert.Equal(t, "s-1", info["sessionId"])
+	assert.Regexp(t, uuidV4Pattern, info["correlationId"])
+	assert.Regexp(t, uuidV4Pattern, info["trackingId"])
+	SetFlowVariable(ctx, CustomFlowInfoKey, info)
+
+	// a later Set and Log keeps the identifiers already in the flow context
+	next := map[string]interface{}{"trackingId": "t-2"}
+	filled = FillMissingIDs(ctx, next, IDStrategyUUIDv4)
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-1", "correlationId": info["correlationId"]}, filled)
+	assert.Equal(t, "t-2", next["trackingId"])
+
+	next = map[string]interface{}{}
+	FillMissingIDs(newRecordContext("fill-2"), next, IDStrategyFlowInstance)
+	assert.Equal(t, map[string]interface{}{"sessionId": "fill-2", "correlationId": "fill-2", "trackingId": "fill-2"}, next)
+}
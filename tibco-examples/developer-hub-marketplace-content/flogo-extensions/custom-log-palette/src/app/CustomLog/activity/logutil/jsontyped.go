@@ -0,0 +1,216 @@
+package logutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Environment variables configuring the "json" format.
+const (
+	EnvKeyJSONTyped     = "FLOGO_CUSTOMLOG_JSON_TYPED"
+	EnvKeyJSONMaxDepth  = "FLOGO_CUSTOMLOG_JSON_MAX_DEPTH"
+	EnvKeyJSONKeepEmpty = "FLOGO_CUSTOMLOG_JSON_KEEP_EMPTY"
+)
+
+// DefaultJSONMaxDepth limits the nesting of typed JSON values.
+const DefaultJSONMaxDepth = 10
+
+// JSONConfig configures the "json" format.
+type JSONConfig struct {
+	// Typed keeps numbers, booleans, arrays and objects as JSON types instead of strings.
+	Typed bool
+	// MaxDepth is the deepest array or object nesting written as JSON (0 = DefaultJSONMaxDepth).
+	// Deeper values are written as a JSON-encoded string.
+	MaxDepth int
+	// KeepEmpty writes keys whose value is "" or nil (as "" and null) instead of skipping them.
+	// It applies to typed mode only.
+	KeepEmpty bool
+}
+
+// JSONConfigFromEnv reads the format configuration from FLOGO_CUSTOMLOG_JSON_* variables.
+func JSONConfigFromEnv() (JSONConfig, error) {
+	var cfg JSONConfig
+	for key, dst := range map[string]*bool{EnvKeyJSONTyped: &cfg.Typed, EnvKeyJSONKeepEmpty: &cfg.KeepEmpty} {
+		if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s [%s]", key, v)
+			}
+			*dst = b
+		}
+	}
+	if v := strings.TrimSpace(os.Getenv(EnvKeyJSONMaxDepth)); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyJSONMaxDepth, v)
+		}
+		cfg.MaxDepth = n
+	}
+	return cfg, nil
+}
+
+var (
+	jsonConfigOnce sync.Once
+	jsonConfig     JSONConfig
+)
+
+// defaultJSONConfig is read once; an invalid environment (reported by ActivityConfig.Validate)
+// keeps the string mode.
+func defaultJSONConfig() JSONConfig {
+	jsonConfigOnce.Do(func() {
+		if cfg, err := JSONConfigFromEnv(); err == nil {
+			jsonConfig = cfg
+		}
+	})
+	return jsonConfig
+}
+
+func (c JSONConfig) maxDepth() int {
+	if c.MaxDepth > 0 {
+		return c.MaxDepth
+	}
+	return DefaultJSONMaxDepth
+}
+
+// isEmptyJSONValue reports values skipped unless KeepEmpty is set.
+func isEmptyJSONValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	s, ok := v.(string)
+	return ok && s == ""
+}
+
+// writeJSONTyped writes v with its JSON type. Maps are written with sorted keys so the
+// output is deterministic; containers nested deeper than depth are written as a string.
+func writeJSONTyped(b *strings.Builder, v interface{}, depth int) {
+	switch x := v.(type) {
+	case nil:
+		b.WriteString("null")
+		return
+	case string:
+		writeJSONString(b, x)
+		return
+	case bool:
+		b.WriteString(strconv.FormatBool(x))
+		return
+	case json.Number:
+		b.WriteString(x.String())
+		return
+	case float64:
+		writeJSONFloat(b, x)
+		return
+	case float32:
+		writeJSONFloat(b, float64(x))
+		return
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprintf(b, "%d", x)
+		return
+	case []byte:
+		writeJSONString(b, string(x))
+		return
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			b.WriteString("null")
+			return
+		}
+		writeJSONTyped(b, rv.Elem().Interface(), depth)
+		return
+	case reflect.Slice, reflect.Array:
+		if depth <= 0 {
+			writeJSONOverflow(b, v)
+			return
+		}
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			b.WriteString("null")
+			return
+		}
+		b.WriteByte('[')
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeJSONTyped(b, rv.Index(i).Interface(), depth-1)
+		}
+		b.WriteByte(']')
+		return
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if depth <= 0 {
+			writeJSONOverflow(b, v)
+			return
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeJSONString(b, k)
+			b.WriteByte(':')
+			writeJSONTyped(b, rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())).Interface(), depth-1)
+		}
+		b.WriteByte('}')
+		return
+	}
+
+	// Structs and other types: use their JSON encoding, then apply the same rules.
+	raw, err := json.Marshal(v)
+	if err != nil {
+		writeJSONString(b, fmt.Sprintf("%v", v))
+		return
+	}
+	generic, ok := decodeJSONValue(string(raw))
+	if !ok {
+		writeJSONString(b, fmt.Sprintf("%v", v))
+		return
+	}
+	writeJSONTyped(b, generic, depth)
+}
+
+// decodeJSONValue decodes s keeping numbers as json.Number.
+func decodeJSONValue(s string) (interface{}, bool) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// writeJSONFloat writes NaN and infinities, which JSON cannot represent, as strings.
+func writeJSONFloat(b *strings.Builder, f float64) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		writeJSONString(b, strconv.FormatFloat(f, 'g', -1, 64))
+		return
+	}
+	b.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// writeJSONOverflow writes a value beyond the depth limit as its compact JSON, in a string.
+func writeJSONOverflow(b *strings.Builder, v interface{}) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		writeJSONString(b, fmt.Sprintf("%v", v))
+		return
+	}
+	writeJSONString(b, string(raw))
+}
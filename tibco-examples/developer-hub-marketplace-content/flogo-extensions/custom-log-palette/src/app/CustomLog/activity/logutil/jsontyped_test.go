@@ -0,0 +1,106 @@
+package logutil
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func typedJSONData() map[string]interface{} {
+	return map[string]interface{}{
+		"message":   "Payment failed",
+		"pid":       42,
+		"amount":    12.5,
+		"retry":     true,
+		"tags":      []interface{}{"a", 1, false},
+		"errorData": map[string]interface{}{"code": 500, "cause": map[string]interface{}{"z": "last", "a": "first"}},
+		"empty":     "",
+		"missing":   nil,
+		"ratio":     math.NaN(),
+	}
+}
+
+func TestFormatJSONStringMode(t *testing.T) {
+	line := formatJSON(typedJSONData(), "ERROR", "flogo.test", fixedTime(), JSONConfig{}, prefixKeys)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &doc))
+	assert.Equal(t, "42", doc["a_pid"])
+	assert.Equal(t, "true", doc["a_retry"])
+	assert.NotContains(t, doc, "a_empty")
+	assert.NotContains(t, doc, "a_missing")
+}
+
+func TestFormatJSONTyped(t *testing.T) {
+	line := formatJSON(typedJSONData(), "ERROR", "flogo.test", fixedTime(), JSONConfig{Typed: true}, prefixKeys)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &doc), line)
+	assert.Equal(t, float64(42), doc["a_pid"])
+	assert.Equal(t, 12.5, doc["a_amount"])
+	assert.Equal(t, true, doc["a_retry"])
+	assert.Equal(t, []interface{}{"a", float64(1), false}, doc["a_tags"])
+	assert.Equal(t, "NaN", doc["a_ratio"])
+	assert.NotContains(t, doc, "a_empty")
+	assert.NotContains(t, doc, "a_missing")
+	// nested objects keep their structure, with sorted keys
+	assert.Contains(t, line, `"a_errorData":{"cause":{"a":"first","z":"last"},"code":500}`)
+	// standard keys keep their position before custom keys
+	assert.Less(t, strings.Index(line, `"a_message"`), strings.Index(line, `"a_errorData"`))
+	assert.Less(t, strings.Index(line, `"a_errorData"`), strings.Index(line, `"a_amount"`))
+
+	// the output is deterministic
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, line, formatJSON(typedJSONData(), "ERROR", "flogo.test", fixedTime(), JSONConfig{Typed: true}, prefixKeys))
+	}
+}
+
+func TestFormatJSONTypedOptions(t *testing.T) {
+	line := formatJSON(typedJSONData(), "ERROR", "flogo.test", fixedTime(), JSONConfig{Typed: true, KeepEmpty: true, MaxDepth: 1}, prefixKeys)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &doc), line)
+	assert.Equal(t, "", doc["a_empty"])
+	assert.Contains(t, doc, "a_missing")
+	assert.Nil(t, doc["a_missing"])
+	// the second level is beyond MaxDepth and is kept as a JSON string
+	assert.Equal(t, map[string]interface{}{"code": float64(500), "cause": `{"a":"first","z":"last"}`}, doc["a_errorData"])
+}
+
+func TestWriteJSONTypedValues(t *testing.T) {
+	type point struct {
+		X int `json:"x"`
+	}
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{[]string{"a", "b"}, `["a","b"]`},
+		{map[string]int{"b": 2, "a": 1}, `{"a":1,"b":2}`},
+		{map[int]string{2: "b"}, `{"2":"b"}`},
+		{point{X: 3}, `{"x":3}`},
+		{&point{X: 4}, `{"x":4}`},
+		{json.Number("1.50"), `1.50`},
+		{[]interface{}(nil), `null`},
+		{uint8(7), `7`},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		writeJSONTyped(&b, tt.v, DefaultJSONMaxDepth)
+		assert.Equal(t, tt.want, b.String())
+	}
+}
+
+func TestJSONConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyJSONTyped, "true")
+	t.Setenv(EnvKeyJSONMaxDepth, "3")
+	cfg, err := JSONConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, JSONConfig{Typed: true, MaxDepth: 3}, cfg)
+
+	t.Setenv(EnvKeyJSONMaxDepth, "0")
+	_, err = JSONConfigFromEnv()
+	assert.Error(t, err)
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidLogFormat)
+}
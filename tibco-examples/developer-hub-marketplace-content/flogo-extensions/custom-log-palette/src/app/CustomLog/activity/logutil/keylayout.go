@@ -0,0 +1,284 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Environment variables configuring the data key layout of the text, json, logfmt and pattern formats.
+const (
+	EnvKeyKeyPrefix   = "FLOGO_CUSTOMLOG_KEY_PREFIX"
+	EnvKeyKeyCase     = "FLOGO_CUSTOMLOG_KEY_CASE"
+	EnvKeyKeySections = "FLOGO_CUSTOMLOG_KEY_SECTIONS"
+	EnvKeyKeyRename   = "FLOGO_CUSTOMLOG_KEY_RENAME"
+)
+
+// DefaultKeyPrefix is prepended to every data key unless FLOGO_CUSTOMLOG_KEY_PREFIX is set (it may be empty).
+const DefaultKeyPrefix = "a_"
+
+// Key cases for KeyLayout.Case.
+const (
+	KeyCaseAsIs  = ""
+	KeyCaseSnake = "snake"
+	KeyCaseCamel = "camel"
+)
+
+// Sections grouping the data keys when KeyLayout.Sections is set.
+const (
+	SectionHeader  = "header"
+	SectionContext = "context"
+	SectionError   = "error"
+	SectionCustom  = "custom"
+)
+
+// sectionOrder is the output order of the sections, after the ungrouped keys.
+var sectionOrder = []string{SectionHeader, SectionContext, SectionError, SectionCustom}
+
+// keySections are the sections of the standard keys. timeStamp, level, message, logFormat
+// and loggerName stay ungrouped. Keys not listed here belong to the section recorded in
+// Entry.Sections (contextParams are SectionContext), else SectionCustom.
+var keySections = map[string]string{
+	"timeStamp": "", "level": "", "message": "", "logFormat": "", "loggerName": "",
+	"applicationName": SectionHeader, "processName": SectionHeader, "jobId": SectionHeader,
+	"processInstanceId": SectionHeader, "parentProcessInstanceId": SectionHeader,
+	"rootProcessInstanceId": SectionHeader, "activityName": SectionHeader, "sessionId": SectionHeader,
+	"correlationId": SectionHeader, "trackingId": SectionHeader, "sender": SectionHeader,
+	"serviceScope": SectionHeader, "flowId": SectionHeader, "traceID": SectionHeader,
+	"spanID": SectionHeader, "traceFlags": SectionHeader, "traceState": SectionHeader,
+	"targetSystem": SectionHeader, "hostName": SectionHeader, "pid": SectionHeader,
+	"containerId": SectionHeader, "podName": SectionHeader, "namespace": SectionHeader,
+	"nodeName": SectionHeader, "appVersion": SectionHeader, "environment": SectionHeader,
+	"errorCode": SectionError, "errorMessage": SectionError, "errorData": SectionError,
+}
+
+// KeyLayout controls the names of the data keys.
+type KeyLayout struct {
+	// Prefix is prepended to each key (DefaultKeyPrefix unless configured; may be empty).
+	Prefix string
+	// Case is KeyCaseAsIs, KeyCaseSnake (process_instance_id) or KeyCaseCamel (processInstanceId).
+	Case string
+	// Sections groups keys into header, context, error and custom: nested objects in
+	// json, dotted names (header.a_sessionId) in text, logfmt and pattern.
+	Sections bool
+	// Renames maps a data key to the exact name written (no prefix or case conversion).
+	Renames map[string]string
+}
+
+// KeyLayoutFromEnv reads the layout from FLOGO_CUSTOMLOG_KEY_* variables.
+// FLOGO_CUSTOMLOG_KEY_RENAME is a list of key=name pairs.
+func KeyLayoutFromEnv() (KeyLayout, error) {
+	l := KeyLayout{
+		Prefix: DefaultKeyPrefix,
+		Case:   strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeyKeyCase))),
+	}
+	if v, ok := os.LookupEnv(EnvKeyKeyPrefix); ok {
+		l.Prefix = strings.TrimSpace(v)
+	}
+	switch l.Case {
+	case KeyCaseAsIs, KeyCaseSnake, KeyCaseCamel:
+	default:
+		return l, fmt.Errorf("invalid %s [%s], valid values=[snake, camel]", EnvKeyKeyCase, l.Case)
+	}
+	if v := strings.TrimSpace(os.Getenv(EnvKeyKeySections)); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return l, fmt.Errorf("invalid %s [%s]", EnvKeyKeySections, v)
+		}
+		l.Sections = b
+	}
+	for _, pair := range splitList(os.Getenv(EnvKeyKeyRename)) {
+		k, name, ok := strings.Cut(pair, "=")
+		k, name = strings.TrimSpace(k), strings.TrimSpace(name)
+		if !ok || k == "" || name == "" {
+			return l, fmt.Errorf("invalid %s entry [%s], expected key=name", EnvKeyKeyRename, pair)
+		}
+		if l.Renames == nil {
+			l.Renames = make(map[string]string)
+		}
+		l.Renames[k] = name
+	}
+	return l, nil
+}
+
+var (
+	keyLayoutOnce sync.Once
+	keyLayout     KeyLayout
+)
+
+// defaultKeyLayout is read once; an invalid environment (reported by ActivityConfig.Validate)
+// keeps the a_ prefix.
+func defaultKeyLayout() KeyLayout {
+	keyLayoutOnce.Do(func() {
+		l, err := KeyLayoutFromEnv()
+		if err != nil {
+			l = KeyLayout{Prefix: DefaultKeyPrefix}
+		}
+		keyLayout = l
+	})
+	return keyLayout
+}
+
+// keyNames names the data keys of one record.
+type keyNames struct {
+	layout KeyLayout
+	// sections are the record's Entry.Sections.
+	sections map[string]string
+}
+
+// defaultKeyNames uses the configured layout for a record with the given sections.
+func defaultKeyNames(sections map[string]string) keyNames {
+	return keyNames{layout: defaultKeyLayout(), sections: sections}
+}
+
+// split returns the section of k ("" when ungrouped or sections are off) and its leaf name.
+func (n keyNames) split(k string) (section, leaf string) {
+	if name, ok := n.layout.Renames[k]; ok {
+		leaf = name
+	} else {
+		leaf = n.layout.Prefix + convertKeyCase(k, n.layout.Case)
+	}
+	if !n.layout.Sections {
+		return "", leaf
+	}
+	if s, ok := keySections[k]; ok {
+		return s, leaf
+	}
+	if s := n.sections[k]; s != "" {
+		return s, leaf
+	}
+	return SectionCustom, leaf
+}
+
+// name is the flat output name of k: section.leaf, or leaf.
+func (n keyNames) name(k string) string {
+	section, leaf := n.split(k)
+	if section == "" {
+		return leaf
+	}
+	return section + "." + leaf
+}
+
+// dataKey maps a written leaf name back to its data key: renames are reversed, the prefix
+// is removed and standard keys get their original case. Other case-converted keys are
+// returned as written. ok is false for names without the prefix.
+func (n keyNames) dataKey(leaf string) (k string, ok bool) {
+	for k, name := range n.layout.Renames {
+		if name == leaf {
+			return k, true
+		}
+	}
+	if !strings.HasPrefix(leaf, n.layout.Prefix) {
+		return "", false
+	}
+	leaf = strings.TrimPrefix(leaf, n.layout.Prefix)
+	if n.layout.Case != KeyCaseAsIs {
+		for k := range keySections {
+			if convertKeyCase(k, n.layout.Case) == leaf {
+				return k, true
+			}
+		}
+	}
+	return leaf, true
+}
+
+func isSection(name string) bool {
+	for _, s := range sectionOrder {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// order returns keys grouped by section (ungrouped keys first, then sectionOrder),
+// keeping their order within a section. Without sections, keys is returned unchanged.
+func (n keyNames) order(keys []string) []string {
+	if !n.layout.Sections {
+		return keys
+	}
+	groups := make(map[string][]string, len(sectionOrder)+1)
+	for _, k := range keys {
+		section, _ := n.split(k)
+		groups[section] = append(groups[section], k)
+	}
+	out := groups[""]
+	for _, section := range sectionOrder {
+		out = append(out, groups[section]...)
+	}
+	return out
+}
+
+// convertKeyCase converts camelCase, snake_case, kebab-case or spaced keys.
+// Runs of capitals are one word: traceID -> trace_id, HTTPStatus -> http_status.
+func convertKeyCase(k, keyCase string) string {
+	if keyCase == KeyCaseAsIs {
+		return k
+	}
+	words := splitKeyWords(k)
+	if len(words) == 0 {
+		return k
+	}
+	if keyCase == KeyCaseSnake {
+		for i := range words {
+			words[i] = strings.ToLower(words[i])
+		}
+		return strings.Join(words, "_")
+	}
+	var b strings.Builder
+	for i, w := range words {
+		if i == 0 {
+			b.WriteString(strings.ToLower(w))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])))
+		if isUpperWord(w) {
+			// keep acronyms as written (traceID)
+			b.WriteString(string(r[1:]))
+		} else {
+			b.WriteString(strings.ToLower(string(r[1:])))
+		}
+	}
+	return b.String()
+}
+
+func splitKeyWords(k string) []string {
+	var words []string
+	var cur []rune
+	runes := []rune(k)
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = cur[:0]
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ' || r == '.':
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(cur) > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
+
+func isUpperWord(w string) bool {
+	for _, r := range w {
+		if unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
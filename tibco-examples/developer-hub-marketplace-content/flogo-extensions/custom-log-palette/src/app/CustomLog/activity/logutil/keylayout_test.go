@@ -0,0 +1,146 @@
+package logutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixKeys is the default layout: a_ prefixed keys, no sections.
+var prefixKeys = keyNames{layout: KeyLayout{Prefix: DefaultKeyPrefix}}
+
+func withKeyLayout(t *testing.T, l KeyLayout) {
+	prev := defaultKeyLayout()
+	keyLayout = l
+	t.Cleanup(func() { keyLayout = prev })
+}
+
+func sectionedData() (map[string]interface{}, map[string]string) {
+	data := map[string]interface{}{
+		"message":           "Payment failed",
+		"level":             "Error",
+		"processInstanceId": "42",
+		"correlationId":     "c-1",
+		"orderId":           "o-9",
+		"errorCode":         "PAY-1",
+		"region":            "eu",
+		"traceID":           "abc",
+	}
+	return data, map[string]string{"orderId": SectionContext, "region": SectionCustom}
+}
+
+func TestConvertKeyCase(t *testing.T) {
+	tests := []struct {
+		key, snake, camel string
+	}{
+		{"processInstanceId", "process_instance_id", "processInstanceId"},
+		{"traceID", "trace_id", "traceID"},
+		{"HTTPStatus", "http_status", "httpStatus"},
+		{"order_id", "order_id", "orderId"},
+		{"x-request-id", "x_request_id", "xRequestId"},
+		{"level", "level", "level"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.snake, convertKeyCase(tt.key, KeyCaseSnake), tt.key)
+		assert.Equal(t, tt.camel, convertKeyCase(tt.key, KeyCaseCamel), tt.key)
+		assert.Equal(t, tt.key, convertKeyCase(tt.key, KeyCaseAsIs))
+	}
+}
+
+func TestKeyNames(t *testing.T) {
+	_, sections := sectionedData()
+	names := keyNames{layout: KeyLayout{Case: KeyCaseSnake, Sections: true, Renames: map[string]string{"traceID": "trace.id"}}, sections: sections}
+	assert.Equal(t, "message", names.name("message"))
+	assert.Equal(t, "header.process_instance_id", names.name("processInstanceId"))
+	assert.Equal(t, "header.trace.id", names.name("traceID"))
+	assert.Equal(t, "context.order_id", names.name("orderId"))
+	assert.Equal(t, "error.error_code", names.name("errorCode"))
+	assert.Equal(t, "custom.region", names.name("region"))
+	assert.Equal(t, "custom.unknown", names.name("unknown"))
+	assert.Equal(t, "a_orderId", prefixKeys.name("orderId"))
+}
+
+func TestFormatJSONSections(t *testing.T) {
+	data, sections := sectionedData()
+	names := keyNames{layout: KeyLayout{Prefix: "", Sections: true}, sections: sections}
+	line := formatJSON(data, "ERROR", "flogo.test", fixedTime(), JSONConfig{}, names)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(line), &doc), line)
+	assert.Equal(t, "Payment failed", doc["message"])
+	assert.Equal(t, map[string]interface{}{"processInstanceId": "42", "correlationId": "c-1", "traceID": "abc"}, doc["header"])
+	assert.Equal(t, map[string]interface{}{"orderId": "o-9"}, doc["context"])
+	assert.Equal(t, map[string]interface{}{"errorCode": "PAY-1"}, doc["error"])
+	assert.Equal(t, map[string]interface{}{"region": "eu"}, doc["custom"])
+	assert.Less(t, strings.Index(line, `"header"`), strings.Index(line, `"context"`))
+	assert.Less(t, strings.Index(line, `"error"`), strings.Index(line, `"custom"`))
+
+	// empty sections are left out
+	line = formatJSON(map[string]interface{}{"message": "m", "region": ""}, "INFO", "flogo.test", fixedTime(), JSONConfig{}, names)
+	assert.NotContains(t, line, "custom")
+}
+
+func TestTextAndLogfmtKeyLayout(t *testing.T) {
+	data, sections := sectionedData()
+	names := keyNames{layout: KeyLayout{Prefix: "x_", Case: KeyCaseSnake, Sections: true}, sections: sections}
+	assert.Equal(t, `x_level="Error", x_message="Payment failed", `+
+		`header.x_process_instance_id="42", header.x_trace_id="abc", header.x_correlation_id="c-1", `+
+		`context.x_order_id="o-9", error.x_error_code="PAY-1", custom.x_region="eu"`,
+		textPairs(data, TextConfig{}, names))
+
+	line := formatLogfmt(map[string]interface{}{"orderId": "o-9", "message": "m"}, "INFO", "flogo.test", fixedTime(),
+		keyNames{layout: KeyLayout{Renames: map[string]string{"orderId": "order"}}})
+	assert.True(t, strings.HasSuffix(line, " message=m order=o-9"), line)
+}
+
+func TestKeyLayoutFromEnv(t *testing.T) {
+	l, err := KeyLayoutFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, KeyLayout{Prefix: DefaultKeyPrefix}, l)
+
+	t.Setenv(EnvKeyKeyPrefix, "")
+	t.Setenv(EnvKeyKeyCase, "Snake")
+	t.Setenv(EnvKeyKeySections, "true")
+	t.Setenv(EnvKeyKeyRename, "traceID=trace.id, correlationId = cid")
+	l, err = KeyLayoutFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, KeyLayout{Case: KeyCaseSnake, Sections: true, Renames: map[string]string{"traceID": "trace.id", "correlationId": "cid"}}, l)
+
+	t.Setenv(EnvKeyKeyRename, "traceID")
+	_, err = KeyLayoutFromEnv()
+	assert.Error(t, err)
+	t.Setenv(EnvKeyKeyRename, "")
+	t.Setenv(EnvKeyKeyCase, "kebab")
+	_, err = KeyLayoutFromEnv()
+	assert.Error(t, err)
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidLogFormat)
+}
+
+func TestEmptyPrefixAndConvertLogLines(t *testing.T) {
+	data, sections := sectionedData()
+	names := keyNames{layout: KeyLayout{Case: KeyCaseSnake, Sections: true}, sections: sections}
+	line := formatJSON(data, "ERROR", "flogo.test", fixedTime(), JSONConfig{}, names)
+	// the record's level is written once
+	assert.Equal(t, 1, strings.Count(line, `"level"`), line)
+
+	k, ok := names.dataKey("process_instance_id")
+	assert.True(t, ok)
+	assert.Equal(t, "processInstanceId", k)
+	k, _ = names.dataKey("order_id")
+	assert.Equal(t, "order_id", k)
+	_, ok = prefixKeys.dataKey("timestamp")
+	assert.False(t, ok)
+
+	withKeyLayout(t, names.layout)
+	data["activityName"] = "Pay"
+	line = formatJSON(data, "ERROR", "flogo.test", fixedTime(), JSONConfig{}, names)
+	l := NewEventLog(EventLogConfig{})
+	n, err := ConvertLogLines(strings.NewReader(line+"\n"), l)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Equal(t, 1, l.Len())
+	assert.Equal(t, "42", l.snapshot()[0].CaseID)
+	assert.Equal(t, "Pay", l.snapshot()[0].Activity)
+}
@@ -0,0 +1,93 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// EnvKeyLogActivityLogLevel is the app-wide minimum level for the Custom Log activities.
+const EnvKeyLogActivityLogLevel = "FLOGO_LOGACTIVITY_LOG_LEVEL"
+
+// Threshold is a minimum log level. Records below it are dropped by the activities
+// before any log data is built or formatted.
+type Threshold int
+
+var filteredRecords atomic.Int64
+
+// LevelRank orders the Custom Log levels (DEBUG < INFO < WARN < ERROR); unknown levels yield -1.
+func LevelRank(level string) int {
+	switch strings.ToUpper(strings.TrimSpace(level)) {
+	case "DEBUG":
+		return 0
+	case "INFO":
+		return 1
+	case "WARN":
+		return 2
+	case "ERROR":
+		return 3
+	}
+	return -1
+}
+
+// ParseThreshold resolves an activity threshold: the per-activity override if set,
+// else FLOGO_LOGACTIVITY_LOG_LEVEL, else DEBUG (everything is logged).
+// An invalid override is an error; an invalid environment value falls back to DEBUG
+// (reported by ActivityConfig.Validate).
+func ParseThreshold(override string) (Threshold, error) {
+	if strings.TrimSpace(override) != "" {
+		rank := LevelRank(override)
+		if rank < 0 {
+			return 0, fmt.Errorf("invalid log level threshold [%s], valid values=[DEBUG, INFO, WARN, ERROR]", override)
+		}
+		return Threshold(rank), nil
+	}
+	threshold, _ := ThresholdFromEnv()
+	return threshold, nil
+}
+
+// ThresholdFromEnv reads the app-wide threshold from FLOGO_LOGACTIVITY_LOG_LEVEL; unset
+// selects DEBUG. An invalid value is an error and yields DEBUG.
+func ThresholdFromEnv() (Threshold, error) {
+	v := os.Getenv(EnvKeyLogActivityLogLevel)
+	if strings.TrimSpace(v) == "" {
+		return Threshold(LevelRank("DEBUG")), nil
+	}
+	if rank := LevelRank(v); rank >= 0 {
+		return Threshold(rank), nil
+	}
+	return Threshold(LevelRank("DEBUG")), fmt.Errorf("invalid %s [%s], valid values=[DEBUG, INFO, WARN, ERROR]", EnvKeyLogActivityLogLevel, v)
+}
+
+// Enabled reports whether a record at level passes the threshold.
+// Unknown levels pass so that the activity can report them as invalid.
+func (t Threshold) Enabled(level string) bool {
+	rank := LevelRank(level)
+	return rank < 0 || rank >= int(t)
+}
+
+// String returns the level name of the threshold.
+func (t Threshold) String() string {
+	switch t {
+	case 0:
+		return "DEBUG"
+	case 1:
+		return "INFO"
+	case 2:
+		return "WARN"
+	case 3:
+		return "ERROR"
+	}
+	return fmt.Sprintf("Threshold(%d)", int(t))
+}
+
+// CountFiltered records a log record dropped by a threshold.
+func CountFiltered() {
+	filteredRecords.Add(1)
+}
+
+// FilteredCount returns the number of records dropped by thresholds since start.
+func FilteredCount() int64 {
+	return filteredRecords.Load()
+}
@@ -0,0 +1,40 @@
+package logutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseThreshold(t *testing.T) {
+	t.Setenv(EnvKeyLogActivityLogLevel, "")
+	th, err := ParseThreshold("")
+	assert.NoError(t, err)
+	assert.Equal(t, "DEBUG", th.String())
+
+	t.Setenv(EnvKeyLogActivityLogLevel, "WARN")
+	th, err = ParseThreshold("")
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN", th.String())
+	assert.False(t, th.Enabled("DEBUG"))
+	assert.False(t, th.Enabled("INFO"))
+	assert.True(t, th.Enabled("WARN"))
+	assert.True(t, th.Enabled("error"))
+	// Unknown levels pass so the activity can report them as invalid
+	assert.True(t, th.Enabled("TRACE"))
+
+	th, err = ParseThreshold("error")
+	assert.NoError(t, err)
+	assert.Equal(t, "ERROR", th.String())
+
+	_, err = ParseThreshold("VERBOSE")
+	assert.Error(t, err)
+
+	t.Setenv(EnvKeyLogActivityLogLevel, "bogus")
+	th, err = ParseThreshold("")
+	assert.NoError(t, err)
+	assert.Equal(t, "DEBUG", th.String())
+	th, err = ThresholdFromEnv()
+	assert.Error(t, err)
+	assert.Equal(t, "DEBUG", th.String())
+}
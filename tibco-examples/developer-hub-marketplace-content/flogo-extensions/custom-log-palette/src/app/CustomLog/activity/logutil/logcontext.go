@@ -0,0 +1,131 @@
+package logutil
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/data"
+	"github.com/project-flogo/flow/instance"
+	"github.com/project-flogo/flow/model"
+)
+
+// Modes of a ContextUpdate.
+const (
+	// ContextMerge adds or overwrites the given keys and keeps the others.
+	ContextMerge = "merge"
+	// ContextReplace replaces the whole context with the given keys.
+	ContextReplace = "replace"
+	// ContextDelete removes the given keys.
+	ContextDelete = "delete"
+)
+
+// ContextUpdate changes the customFlowInfo of a flow instance without logging.
+type ContextUpdate struct {
+	// Mode is ContextMerge (default), ContextReplace or ContextDelete.
+	Mode string
+	// Values are the Header and contextParams keys merged or replaced.
+	Values map[string]interface{}
+	// Keys are the keys removed by ContextDelete.
+	Keys []string
+	// Scoped limits the update to the calling subflow instance (e.g. the subflow of an
+	// iterator): the context it had before its first scoped update is restored when
+	// the instance ends.
+	Scoped bool
+}
+
+// scopedContext is the context saved by the first scoped update of a subflow instance.
+type scopedContext struct {
+	owner *instance.Instance
+	scope data.Scope
+	saved map[string]interface{}
+}
+
+// scopedContexts holds the saved contexts in the order of their updates. An entry is
+// restored by the first context access after its instance has ended, on the goroutine
+// of the flow that reads it, so the parent flow never sees the subflow's scoped changes.
+var scopedContexts struct {
+	sync.Mutex
+	entries []*scopedContext
+}
+
+// UpdateCustomFlowInfo applies u to the customFlowInfo of the calling flow and returns the
+// resulting context (see CustomFlowInfo). A subflow changes the context it shares with the
+// flow that started it unless the update is scoped. It fails when the calling host has no
+// flow context to store into.
+func UpdateCustomFlowInfo(ctx activity.Context, u ContextUpdate) (map[string]interface{}, error) {
+	mode := strings.ToLower(strings.TrimSpace(u.Mode))
+	if mode == "" {
+		mode = ContextMerge
+	}
+	if err := ValidateContextMode(mode); err != nil {
+		return nil, err
+	}
+	scope := flowScope(ctx)
+	if scope == nil {
+		return nil, activity.NewActivityError("customFlowInfo cannot be updated: the activity host is not a flow instance and has no instance ID.",
+			ErrCodeInvalidContext, activity.ActivityError, nil)
+	}
+	current, _ := scopeValue(scope, CustomFlowInfoKey)
+	info, _ := current.(map[string]interface{})
+	if u.Scoped {
+		saveScopedContext(ctx, scope, info)
+	}
+
+	next := copyFlowInfo(info)
+	switch mode {
+	case ContextMerge:
+		for k, v := range u.Values {
+			next[k] = v
+		}
+	case ContextReplace:
+		next = copyFlowInfo(u.Values)
+	case ContextDelete:
+		for _, k := range u.Keys {
+			delete(next, k)
+		}
+	}
+	SetFlowVariable(ctx, CustomFlowInfoKey, next)
+	return CustomFlowInfo(ctx), nil
+}
+
+// saveScopedContext saves info for the calling subflow instance unless it already has a
+// saved context. A main flow, or a host that is not a flow instance, owns its context for
+// its whole life, so nothing is saved.
+func saveScopedContext(ctx activity.Context, scope data.Scope, info map[string]interface{}) {
+	inst, ok := ctx.ActivityHost().Scope().(*instance.Instance)
+	if master, _ := scope.(*instance.IndependentInstance); !ok || master == nil || master.Instance == inst {
+		return
+	}
+	scopedContexts.Lock()
+	defer scopedContexts.Unlock()
+	for _, e := range scopedContexts.entries {
+		if e.owner == inst {
+			return
+		}
+	}
+	scopedContexts.entries = append(scopedContexts.entries, &scopedContext{owner: inst, scope: scope, saved: copyFlowInfo(info)})
+}
+
+// restoreScopedContexts restores the contexts saved for subflow instances that have ended,
+// latest first, so that nested scoped subflows unwind in order.
+func restoreScopedContexts() {
+	scopedContexts.Lock()
+	defer scopedContexts.Unlock()
+	entries := scopedContexts.entries
+	for i := len(entries) - 1; i >= 0; i-- {
+		if e := entries[i]; e.owner.Status() >= model.FlowStatusCompleted {
+			_ = e.scope.SetValue("TIB_Flow:"+CustomFlowInfoKey, e.saved)
+			entries = append(entries[:i], entries[i+1:]...)
+		}
+	}
+	scopedContexts.entries = entries
+}
+
+func copyFlowInfo(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package logutil
+
+import (
+	"testing"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateCustomFlowInfo(t *testing.T) {
+	ctx := newRecordContext("7")
+	t.Cleanup(func() { ReleaseFlowContext("7") })
+	SetFlowVariable(ctx, CustomFlowInfoKey, map[string]interface{}{"correlationId": "c-1", "region": "eu"})
+
+	info, err := UpdateCustomFlowInfo(ctx, ContextUpdate{Values: map[string]interface{}{"orderId": "o-9", "region": "us"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"correlationId": "c-1", "orderId": "o-9", "region": "us"}, info)
+
+	info, err = UpdateCustomFlowInfo(ctx, ContextUpdate{Mode: "Delete", Keys: []string{"region", "unknown"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"correlationId": "c-1", "orderId": "o-9"}, info)
+
+	info, err = UpdateCustomFlowInfo(ctx, ContextUpdate{Mode: ContextReplace, Values: map[string]interface{}{"sessionId": "s-1"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-1"}, info)
+}
+
+func TestUpdateCustomFlowInfoScoped(t *testing.T) {
+	var infoA, infoB, afterB, afterA map[string]interface{}
+	runFlowChain(t, map[string]func(ctx activity.Context){
+		"main": func(ctx activity.Context) {
+			SetFlowVariable(ctx, CustomFlowInfoKey, map[string]interface{}{"sessionId": "s-main"})
+			// a main flow owns its context: a scoped update there is kept
+			_, err := UpdateCustomFlowInfo(ctx, ContextUpdate{Values: map[string]interface{}{"region": "eu"}, Scoped: true})
+			assert.NoError(t, err)
+		},
+		"a": func(ctx activity.Context) {
+			infoA, _ = UpdateCustomFlowInfo(ctx, ContextUpdate{Values: map[string]interface{}{"itemId": "i-a"}, Scoped: true})
+		},
+		"b": func(ctx activity.Context) {
+			// only the first scoped update of an instance saves the context
+			for _, step := range []string{"b-1", "b-2"} {
+				_, err := UpdateCustomFlowInfo(ctx, ContextUpdate{Values: map[string]interface{}{"itemId": "i-b", "step": step}, Scoped: true})
+				assert.NoError(t, err)
+			}
+			_, err := UpdateCustomFlowInfo(ctx, ContextUpdate{Mode: ContextDelete, Keys: []string{"region"}})
+			assert.NoError(t, err)
+			infoB = CustomFlowInfo(ctx)
+		},
+		"a:after": func(ctx activity.Context) {
+			afterB = CustomFlowInfo(ctx)
+		},
+		"main:after": func(ctx activity.Context) {
+			afterA = CustomFlowInfo(ctx)
+		},
+	})
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-main", "region": "eu", "itemId": "i-a"}, infoA)
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-main", "itemId": "i-b", "step": "b-2"}, infoB)
+	// restored automatically when each subflow ends
+	assert.Equal(t, infoA, afterB)
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-main", "region": "eu"}, afterA)
+	assert.Empty(t, scopedContexts.entries)
+
+	// a host that is not a flow instance has no subflows: the update is kept
+	ctx := newRecordContext("7")
+	t.Cleanup(func() { ReleaseFlowContext("7") })
+	info, err := UpdateCustomFlowInfo(ctx, ContextUpdate{Values: map[string]interface{}{"itemId": "i-1"}, Scoped: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"itemId": "i-1"}, info)
+
+	_, err = UpdateCustomFlowInfo(ctx, ContextUpdate{Mode: "restore"})
+	assertErrorCode(t, err, ErrCodeInvalidContext)
+	assert.NoError(t, ValidateContextMode(""))
+}
+
+func TestUpdateCustomFlowInfoNestedSubflow(t *testing.T) {
+	var infoA, infoB map[string]interface{}
+	runFlowChain(t, map[string]func(ctx activity.Context){
+		"main": func(ctx activity.Context) {
+			SetFlowVariable(ctx, CustomFlowInfoKey, map[string]interface{}{"sessionId": "s-main", "region": "eu"})
+		},
+		"a": func(ctx activity.Context) {
+			_, err := UpdateCustomFlowInfo(ctx, ContextUpdate{Values: map[string]interface{}{"orderId": "o-a"}})
+			assert.NoError(t, err)
+		},
+		"b": func(ctx activity.Context) {
+			// orderId comes from the intermediate subflow A, region from main
+			info, err := UpdateCustomFlowInfo(ctx, ContextUpdate{Mode: ContextDelete, Keys: []string{"orderId", "region"}})
+			assert.NoError(t, err)
+			infoB = info
+		},
+		"a:after": func(ctx activity.Context) {
+			infoA = CustomFlowInfo(ctx)
+		},
+	})
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-main"}, infoB)
+	// subflows share the context of the main flow
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-main"}, infoA)
+}
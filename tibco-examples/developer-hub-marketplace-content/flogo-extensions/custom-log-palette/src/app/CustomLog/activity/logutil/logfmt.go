@@ -0,0 +1,98 @@
+package logutil
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// formatLogfmt writes ts, level and logger, then the data keys (named by names, "a_key" by
+// default) in OrderedKeys order. Empty values are skipped, as in the other formats.
+func formatLogfmt(logData map[string]interface{}, levelUpper string, loggerName string, now time.Time, names keyNames) string {
+	var b strings.Builder
+	b.Grow(512)
+	appendLogfmtPair(&b, "ts", defaultTimestampConfig().Format(now))
+	appendLogfmtPair(&b, "level", levelUpper)
+	appendLogfmtPair(&b, "logger", loggerName)
+	for _, k := range names.order(OrderedKeys(logData)) {
+		name := names.name(k)
+		if name == "ts" || name == "level" || name == "logger" {
+			// with an empty prefix, a data key named like a record key is left out
+			continue
+		}
+		if s := toString(logData[k]); s != "" {
+			appendLogfmtPair(&b, name, s)
+		}
+	}
+	return b.String()
+}
+
+func appendLogfmtPair(b *strings.Builder, key, val string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	writeLogfmtKey(b, key)
+	b.WriteByte('=')
+	writeLogfmtValue(b, val)
+}
+
+// writeLogfmtKey replaces the characters logfmt does not allow in keys
+// (space, '=', '"', control characters) with '_'.
+func writeLogfmtKey(b *strings.Builder, key string) {
+	if key == "" {
+		b.WriteByte('_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || r == 0x7f {
+			b.WriteByte('_')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+}
+
+// writeLogfmtValue writes val bare when possible, otherwise as a double-quoted string
+// with JSON-style escapes, as go-logfmt and Loki expect.
+func writeLogfmtValue(b *strings.Builder, val string) {
+	if !logfmtNeedsQuote(val) {
+		b.WriteString(val)
+		return
+	}
+	const hex = "0123456789abcdef"
+	b.WriteByte('"')
+	for _, r := range val {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' || r == 0x7f {
+				b.WriteString(`\u00`)
+				b.WriteByte(hex[r>>4])
+				b.WriteByte(hex[r&0xf])
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+func logfmtNeedsQuote(val string) bool {
+	if val == "" {
+		return true
+	}
+	for _, r := range val {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package logutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseLogfmt decodes one logfmt line into its key/value pairs, in order.
+func parseLogfmt(line string) ([][2]string, error) {
+	var pairs [][2]string
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+		eq := strings.IndexByte(line[i:], '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("missing '=' at %d", i)
+		}
+		key := line[i : i+eq]
+		i += eq + 1
+		var val string
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated value for %s", key)
+			}
+			unquoted, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("value for %s: %v", key, err)
+			}
+			val, i = unquoted, end+1
+		} else {
+			end := strings.IndexByte(line[i:], ' ')
+			if end < 0 {
+				end = len(line) - i
+			}
+			val, i = line[i:i+end], i+end
+		}
+		pairs = append(pairs, [2]string{key, val})
+	}
+	return pairs, nil
+}
+
+func TestFormatLogfmtRoundTrip(t *testing.T) {
+	logData := map[string]interface{}{
+		"applicationName": "orders",
+		"processName":     "Create Order",
+		"jobId":           "4711",
+		"level":           "Warn",
+		"message":         "stock low: \"sku=42\"\n\tretrying in 5s \\ backoff",
+		"orderId":         "o=9",
+		"amount":          12.5,
+		"control":         "bell\a end",
+		"unicode":         "größe ✓",
+		"empty":           "",
+		"zeta":            "last",
+	}
+	now := time.Date(2026, 2, 13, 16, 53, 47, 498000000, time.UTC)
+	line := formatCustomLogAt(logData, nil, " LogFmt ", "warn", "flogo.orders", now)
+	assert.NotContains(t, line, "\n")
+
+	pairs, err := parseLogfmt(line)
+	require.NoError(t, err)
+	require.True(t, len(pairs) >= 3)
+	assert.Equal(t, [2]string{"ts", "2026-02-13T16:53:47.498Z"}, pairs[0])
+	assert.Equal(t, [2]string{"level", "WARN"}, pairs[1])
+	assert.Equal(t, [2]string{"logger", "flogo.orders"}, pairs[2])
+
+	decoded := make(map[string]interface{})
+	var keys []string
+	for _, p := range pairs[3:] {
+		require.True(t, strings.HasPrefix(p[0], "a_"), p[0])
+		keys = append(keys, strings.TrimPrefix(p[0], "a_"))
+		decoded[strings.TrimPrefix(p[0], "a_")] = p[1]
+	}
+	expected := make(map[string]interface{})
+	for k, v := range logData {
+		if s := toString(v); s != "" {
+			expected[k] = s
+		}
+	}
+	assert.Equal(t, expected, decoded)
+
+	var want []string
+	for _, k := range OrderedKeys(logData) {
+		if toString(logData[k]) != "" {
+			want = append(want, k)
+		}
+	}
+	assert.Equal(t, want, keys, "logfmt follows the standard key order")
+}
+
+func TestWriteLogfmt(t *testing.T) {
+	var b strings.Builder
+	appendLogfmtPair(&b, "bad key=\"x\"", "plain")
+	appendLogfmtPair(&b, "k", "")
+	appendLogfmtPair(&b, "q", `a "b"`)
+	assert.Equal(t, `bad_key__x_=plain k="" q="a \"b\""`, b.String())
+}
@@ -0,0 +1,96 @@
+package logutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/engine"
+	"github.com/project-flogo/core/support/log"
+)
+
+// NewActivityLogger creates the logger of one activity instance. Fields that do not change
+// between calls (activity, flow and app names) are bound once here; per-call fields are
+// added by CallLogger. The logger level follows threshold.
+func NewActivityLogger(name string, ctx activity.InitContext, threshold Threshold) log.Logger {
+	var logger log.Logger
+	if log.CtxLoggingEnabled() {
+		logger = log.NewLoggerWithFields(name, StaticLogFields(ctx)...)
+	} else {
+		logger = log.NewLogger(name)
+	}
+	log.SetLogLevel(logger, threshold.logLevel())
+	return logger
+}
+
+// StaticLogFields returns the context fields shared by every call of an activity instance.
+func StaticLogFields(ctx activity.InitContext) []log.Field {
+	fields := []log.Field{
+		log.FieldString("activity.name", ctx.Name()),
+		log.FieldString("flow.name", ctx.HostName()),
+		log.FieldString("app.name", engine.GetAppName()),
+		log.FieldString("app.version", engine.GetAppVersion()),
+	}
+	if engine.GetEnvName() != "" {
+		fields = append(fields, log.FieldString("deployment.environment", engine.GetEnvName()))
+	}
+	return fields
+}
+
+// CallLogFields returns the context fields of a single call: the flow instance ID and,
+// when tracing is enabled, the trace and span IDs.
+func CallLogFields(ctx activity.Context) []log.Field {
+	fields := []log.Field{log.FieldString("flow.id", ctx.ActivityHost().ID())}
+	if tc := ctx.GetTracingContext(); tc != nil {
+		fields = append(fields, log.FieldString("trace.id", tc.TraceID()), log.FieldString("span.id", tc.SpanID()))
+	}
+	return fields
+}
+
+// CallLogger returns base with the fields of the current call attached. The child logger
+// is local to the call, so concurrent flows never see each other's IDs.
+func CallLogger(base log.Logger, ctx activity.Context) log.Logger {
+	if !log.CtxLoggingEnabled() {
+		return base
+	}
+	return log.ChildLoggerWithFields(base, CallLogFields(ctx)...)
+}
+
+// warnInterval is the shortest time between two warnings of a warnLimiter.
+const warnInterval = time.Minute
+
+// warnLimiter rate-limits the engine warnings about records a sink or the async pipeline
+// lost: the first loss is reported at once, later ones as a count at most once per
+// warnInterval.
+type warnLimiter struct {
+	mu      sync.Mutex
+	last    time.Time
+	pending int64
+}
+
+// add counts n lost records and returns the number to report now, or 0 while the
+// interval since the last warning has not elapsed.
+func (l *warnLimiter) add(n int64) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending += n
+	now := time.Now()
+	if !l.last.IsZero() && now.Sub(l.last) < warnInterval {
+		return 0
+	}
+	l.last = now
+	n, l.pending = l.pending, 0
+	return n
+}
+
+func (t Threshold) logLevel() log.Level {
+	switch t {
+	case 1:
+		return log.InfoLevel
+	case 2:
+		return log.WarnLevel
+	case 3:
+		return log.ErrorLevel
+	}
+	return log.DebugLevel
+}
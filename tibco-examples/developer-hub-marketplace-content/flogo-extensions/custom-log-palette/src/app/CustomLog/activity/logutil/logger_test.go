@@ -0,0 +1,69 @@
+package logutil
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/data"
+	"github.com/project-flogo/core/support/test"
+	"github.com/project-flogo/core/support/trace"
+	"github.com/stretchr/testify/assert"
+)
+
+// testTracingContext is a trace.TracingContext with fixed IDs.
+type testTracingContext struct {
+	traceID, spanID string
+}
+
+func (c *testTracingContext) TraceObject() interface{}                  { return nil }
+func (c *testTracingContext) SetTags(tags map[string]interface{}) bool  { return false }
+func (c *testTracingContext) SetTag(key string, value interface{}) bool { return false }
+func (c *testTracingContext) LogKV(kvs map[string]interface{}) bool     { return false }
+func (c *testTracingContext) TraceID() string                           { return c.traceID }
+func (c *testTracingContext) SpanID() string                            { return c.spanID }
+
+// tracedActivityContext attaches a tracing context to a test activity context.
+type tracedActivityContext struct {
+	*test.TestActivityContext
+	tc trace.TracingContext
+}
+
+func (c *tracedActivityContext) GetTracingContext() trace.TracingContext {
+	return c.tc
+}
+
+func TestCallLogFieldsParallel(t *testing.T) {
+	base := NewActivityLogger("flogo.CustomLog.test", test.NewActivityInitContext(nil, nil), 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			host := &test.TestActivityHost{HostId: "flow-" + id, HostData: data.NewSimpleScope(nil, nil)}
+			ctx := &tracedActivityContext{
+				TestActivityContext: test.NewActivityContextWithAction(activity.ToMetadata(), host),
+				tc:                  &testTracingContext{traceID: "trace-" + id, spanID: "span-" + id},
+			}
+			fields := fmt.Sprint(CallLogFields(ctx))
+			assert.Contains(t, fields, "flow-"+id+" ")
+			assert.Contains(t, fields, "trace-"+id+" ")
+			assert.Contains(t, fields, "span-"+id+" ")
+			assert.NotNil(t, CallLogger(base, ctx))
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+}
+
+func TestWarnLimiter(t *testing.T) {
+	var l warnLimiter
+	assert.Equal(t, int64(1), l.add(1), "the first loss is reported at once")
+	assert.Zero(t, l.add(2))
+	assert.Zero(t, l.add(3))
+	// once the interval has elapsed, the losses since the last warning are reported together
+	l.last = l.last.Add(-warnInterval)
+	assert.Equal(t, int64(6), l.add(1))
+	assert.Zero(t, l.add(1))
+}
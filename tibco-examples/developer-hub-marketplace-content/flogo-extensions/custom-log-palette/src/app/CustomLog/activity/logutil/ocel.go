@@ -0,0 +1,159 @@
+package logutil
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// OCEL 2.0 JSON document (https://www.ocel-standard.org/).
+type ocelDocument struct {
+	ObjectTypes []ocelType   `json:"objectTypes"`
+	EventTypes  []ocelType   `json:"eventTypes"`
+	Objects     []ocelObject `json:"objects"`
+	Events      []ocelEvent  `json:"events"`
+}
+
+type ocelType struct {
+	Name       string          `json:"name"`
+	Attributes []ocelAttribute `json:"attributes"`
+}
+
+type ocelAttribute struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ocelObject struct {
+	ID            string             `json:"id"`
+	Type          string             `json:"type"`
+	Attributes    []ocelValue        `json:"attributes"`
+	Relationships []ocelRelationship `json:"relationships"`
+}
+
+type ocelEvent struct {
+	ID            string             `json:"id"`
+	Type          string             `json:"type"`
+	Time          string             `json:"time"`
+	Attributes    []ocelValue        `json:"attributes"`
+	Relationships []ocelRelationship `json:"relationships"`
+}
+
+type ocelValue struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type ocelRelationship struct {
+	ObjectID  string `json:"objectId"`
+	Qualifier string `json:"qualifier"`
+}
+
+// WriteOCEL writes the log as an OCEL 2.0 JSON document. The activity name is the event
+// type. The case key and each configured object key are object types; an event relates to
+// one object per key it carries, with ID "<key>:<value>" and the key as qualifier. Those
+// keys are not repeated as event attributes. An attribute whose values have mixed types
+// across events of a type is declared and written as a string.
+func (l *EventLog) WriteOCEL(w io.Writer) error {
+	events := l.snapshot()
+	doc := ocelDocument{
+		ObjectTypes: []ocelType{},
+		EventTypes:  []ocelType{},
+		Objects:     []ocelObject{},
+		Events:      make([]ocelEvent, 0, len(events)),
+	}
+
+	objectKeys := append(append([]string(nil), l.cfg.CaseKeys...), l.cfg.ObjectKeys...)
+	isObjectKey := make(map[string]bool, len(objectKeys))
+	for _, k := range objectKeys {
+		isObjectKey[k] = true
+	}
+
+	// attribute types per event type, in first-seen order
+	var eventTypes []string
+	attrTypes := make(map[string]map[string]string)
+	attrOrder := make(map[string][]string)
+	for _, ev := range events {
+		types, ok := attrTypes[ev.Activity]
+		if !ok {
+			types = make(map[string]string)
+			attrTypes[ev.Activity] = types
+			eventTypes = append(eventTypes, ev.Activity)
+		}
+		for _, k := range OrderedKeys(ev.Attributes) {
+			if isObjectKey[k] {
+				continue
+			}
+			typ := eventLogValueType(ev.Attributes[k])
+			if prev, seen := types[k]; !seen {
+				types[k] = typ
+				attrOrder[ev.Activity] = append(attrOrder[ev.Activity], k)
+			} else if prev != typ {
+				types[k] = "string"
+			}
+		}
+	}
+	for _, name := range eventTypes {
+		t := ocelType{Name: name, Attributes: []ocelAttribute{}}
+		for _, k := range attrOrder[name] {
+			t.Attributes = append(t.Attributes, ocelAttribute{Name: k, Type: attrTypes[name][k]})
+		}
+		doc.EventTypes = append(doc.EventTypes, t)
+	}
+
+	usedTypes := make(map[string]bool)
+	seenObjects := make(map[string]bool)
+	for _, ev := range events {
+		oe := ocelEvent{
+			ID:            ev.ID,
+			Type:          ev.Activity,
+			Time:          eventLogTime(ev.Time),
+			Attributes:    []ocelValue{},
+			Relationships: []ocelRelationship{},
+		}
+		for _, k := range OrderedKeys(ev.Attributes) {
+			if isObjectKey[k] {
+				continue
+			}
+			v := ev.Attributes[k]
+			if attrTypes[ev.Activity][k] == "string" {
+				v = toString(v)
+			}
+			oe.Attributes = append(oe.Attributes, ocelValue{Name: k, Value: v})
+		}
+		for _, k := range objectKeys {
+			value := ev.CaseID
+			if k != ev.CaseKey {
+				if value = toString(ev.Attributes[k]); value == "" {
+					continue
+				}
+			}
+			id := k + ":" + value
+			oe.Relationships = append(oe.Relationships, ocelRelationship{ObjectID: id, Qualifier: k})
+			usedTypes[k] = true
+			if !seenObjects[id] {
+				seenObjects[id] = true
+				doc.Objects = append(doc.Objects, ocelObject{
+					ID:            id,
+					Type:          k,
+					Attributes:    []ocelValue{},
+					Relationships: []ocelRelationship{},
+				})
+			}
+		}
+		doc.Events = append(doc.Events, oe)
+	}
+
+	var types []string
+	for k := range usedTypes {
+		types = append(types, k)
+	}
+	sort.Strings(types)
+	for _, k := range types {
+		doc.ObjectTypes = append(doc.ObjectTypes, ocelType{Name: k, Attributes: []ocelAttribute{}})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
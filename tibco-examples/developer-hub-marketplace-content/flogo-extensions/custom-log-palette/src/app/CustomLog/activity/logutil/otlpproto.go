@@ -0,0 +1,216 @@
+package logutil
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Minimal protobuf encoder for opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest.
+// Only the fields written by the Custom Log palette are implemented, which keeps the
+// palette free of the OpenTelemetry SDK and gRPC dependency trees.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// OTLP SeverityNumber values for the Custom Log levels.
+const (
+	otlpSeverityDebug = 5
+	otlpSeverityInfo  = 9
+	otlpSeverityWarn  = 13
+	otlpSeverityError = 17
+)
+
+// otlpLogRecord is a LogRecord captured from an Entry.
+type otlpLogRecord struct {
+	timeUnixNano   uint64
+	severityNumber int
+	severityText   string
+	body           string
+	attributes     map[string]interface{}
+	traceID        []byte
+	spanID         []byte
+}
+
+// OTLPSeverity maps a Custom Log level to an OTLP SeverityNumber.
+func OTLPSeverity(level string) int {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return otlpSeverityDebug
+	case "INFO":
+		return otlpSeverityInfo
+	case "WARN":
+		return otlpSeverityWarn
+	case "ERROR":
+		return otlpSeverityError
+	}
+	return 0
+}
+
+// decodeTraceBytes converts a hex trace/span ID to bytes; invalid or all-zero IDs yield nil.
+func decodeTraceBytes(id string, size int) []byte {
+	b, err := hex.DecodeString(id)
+	if err != nil || len(b) != size {
+		return nil
+	}
+	for _, c := range b {
+		if c != 0 {
+			return b
+		}
+	}
+	return nil
+}
+
+type protoBuf struct {
+	b []byte
+}
+
+func (p *protoBuf) tag(field, wire int) {
+	p.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (p *protoBuf) varint(v uint64) {
+	p.b = binary.AppendUvarint(p.b, v)
+}
+
+func (p *protoBuf) stringField(field int, s string) {
+	if s == "" {
+		return
+	}
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(s)))
+	p.b = append(p.b, s...)
+}
+
+func (p *protoBuf) bytesField(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(b)))
+	p.b = append(p.b, b...)
+}
+
+func (p *protoBuf) messageField(field int, encode func(*protoBuf)) {
+	var inner protoBuf
+	encode(&inner)
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(inner.b)))
+	p.b = append(p.b, inner.b...)
+}
+
+func (p *protoBuf) varintField(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, wireVarint)
+	p.varint(v)
+}
+
+func (p *protoBuf) fixed64Field(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	p.tag(field, wireFixed64)
+	p.b = binary.LittleEndian.AppendUint64(p.b, v)
+}
+
+// encodeExportLogsRequest encodes one ResourceLogs with one ScopeLogs holding records.
+func encodeExportLogsRequest(resource map[string]interface{}, scopeName, scopeVersion string, records []*otlpLogRecord) []byte {
+	var p protoBuf
+	// ExportLogsServiceRequest.resource_logs = 1
+	p.messageField(1, func(rl *protoBuf) {
+		// ResourceLogs.resource = 1
+		rl.messageField(1, func(r *protoBuf) {
+			encodeAttributes(r, 1, resource)
+		})
+		// ResourceLogs.scope_logs = 2
+		rl.messageField(2, func(sl *protoBuf) {
+			// ScopeLogs.scope = 1
+			sl.messageField(1, func(s *protoBuf) {
+				s.stringField(1, scopeName)
+				s.stringField(2, scopeVersion)
+			})
+			// ScopeLogs.log_records = 2
+			for _, rec := range records {
+				sl.messageField(2, func(lr *protoBuf) { encodeLogRecord(lr, rec) })
+			}
+		})
+	})
+	return p.b
+}
+
+func encodeLogRecord(p *protoBuf, rec *otlpLogRecord) {
+	p.fixed64Field(1, rec.timeUnixNano)
+	p.varintField(2, uint64(rec.severityNumber))
+	p.stringField(3, rec.severityText)
+	p.messageField(5, func(v *protoBuf) { encodeAnyValue(v, rec.body) })
+	encodeAttributes(p, 6, rec.attributes)
+	p.bytesField(9, rec.traceID)
+	p.bytesField(10, rec.spanID)
+	p.fixed64Field(11, rec.timeUnixNano)
+}
+
+// encodeAttributes writes attrs as repeated KeyValue in key order, so output is deterministic.
+func encodeAttributes(p *protoBuf, field int, attrs map[string]interface{}) {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := attrs[k]
+		p.messageField(field, func(kv *protoBuf) {
+			kv.stringField(1, k)
+			kv.messageField(2, func(av *protoBuf) { encodeAnyValue(av, v) })
+		})
+	}
+}
+
+// encodeAnyValue writes an AnyValue, keeping numbers, booleans, lists and maps typed.
+func encodeAnyValue(p *protoBuf, v interface{}) {
+	switch x := v.(type) {
+	case nil:
+	case string:
+		p.tag(1, wireBytes)
+		p.varint(uint64(len(x)))
+		p.b = append(p.b, x...)
+	case bool:
+		p.tag(2, wireVarint)
+		if x {
+			p.varint(1)
+		} else {
+			p.varint(0)
+		}
+	case int, int8, int16, int32, int64:
+		p.tag(3, wireVarint)
+		p.varint(uint64(reflect.ValueOf(x).Int()))
+	case uint, uint8, uint16, uint32, uint64:
+		p.tag(3, wireVarint)
+		p.varint(reflect.ValueOf(x).Uint())
+	case float32, float64:
+		p.tag(4, wireFixed64)
+		p.b = binary.LittleEndian.AppendUint64(p.b, math.Float64bits(reflect.ValueOf(x).Float()))
+	case []interface{}:
+		p.messageField(5, func(arr *protoBuf) {
+			for _, item := range x {
+				arr.messageField(1, func(av *protoBuf) { encodeAnyValue(av, item) })
+			}
+		})
+	case map[string]interface{}:
+		p.messageField(6, func(kvl *protoBuf) { encodeAttributes(kvl, 1, x) })
+	case []byte:
+		p.tag(7, wireBytes)
+		p.varint(uint64(len(x)))
+		p.b = append(p.b, x...)
+	default:
+		encodeAnyValue(p, toString(v))
+	}
+}
@@ -0,0 +1,470 @@
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/project-flogo/core/engine"
+	"github.com/project-flogo/core/support/log"
+)
+
+// Environment variables configuring the "otlp" sink. The standard OTEL_EXPORTER_OTLP_*
+// variables are used as fallbacks for endpoint, headers and protocol.
+const (
+	EnvKeyOTLPEndpoint     = "FLOGO_CUSTOMLOG_OTLP_ENDPOINT"
+	EnvKeyOTLPProtocol     = "FLOGO_CUSTOMLOG_OTLP_PROTOCOL"
+	EnvKeyOTLPHeaders      = "FLOGO_CUSTOMLOG_OTLP_HEADERS"
+	EnvKeyOTLPTimeout      = "FLOGO_CUSTOMLOG_OTLP_TIMEOUT"
+	EnvKeyOTLPBatchSize    = "FLOGO_CUSTOMLOG_OTLP_BATCH_SIZE"
+	EnvKeyOTLPBatchTimeout = "FLOGO_CUSTOMLOG_OTLP_BATCH_TIMEOUT"
+	EnvKeyOTLPQueueSize    = "FLOGO_CUSTOMLOG_OTLP_QUEUE_SIZE"
+	EnvKeyOTLPMaxRetries   = "FLOGO_CUSTOMLOG_OTLP_MAX_RETRIES"
+)
+
+// OTLP transport protocols.
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+)
+
+const (
+	otlpScopeName = "flogo.CustomLog"
+	otlpGRPCPath  = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+	otlpHTTPPath  = "/v1/logs"
+)
+
+func init() {
+	RegisterSink("otlp", func() (Sink, error) {
+		cfg, err := OTLPSinkConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewOTLPSink(cfg)
+	})
+}
+
+// OTLPSinkConfig configures an OTLPSink.
+type OTLPSinkConfig struct {
+	// Endpoint is the collector URL. For http/protobuf a URL without path gets /v1/logs appended.
+	Endpoint string
+	// Protocol is OTLPProtocolHTTP or OTLPProtocolGRPC.
+	Protocol string
+	// Headers are sent with every export request (e.g. authentication).
+	Headers map[string]string
+	// Resource holds the resource attributes (service.name, service.version, ...).
+	Resource map[string]interface{}
+	// Timeout bounds a single export request.
+	Timeout time.Duration
+	// BatchSize is the maximum number of records per export request.
+	BatchSize int
+	// BatchTimeout is the maximum time a record waits before its batch is exported.
+	BatchTimeout time.Duration
+	// QueueSize bounds the records waiting for export; Write fails when it is full.
+	QueueSize int
+	// MaxRetries is the number of retries for retryable failures, with exponential backoff.
+	MaxRetries int
+	// Client overrides the HTTP client (tests, custom TLS).
+	Client *http.Client
+}
+
+// OTLPResource returns the resource attributes describing the running Flogo app.
+func OTLPResource() map[string]interface{} {
+	res := map[string]interface{}{
+		"service.name":    engine.GetAppName(),
+		"service.version": engine.GetAppVersion(),
+	}
+	if env := engine.GetEnvName(); env != "" {
+		res["deployment.environment"] = env
+	}
+	return res
+}
+
+// OTLPSinkConfigFromEnv reads the OTLP sink configuration from the environment.
+func OTLPSinkConfigFromEnv() (OTLPSinkConfig, error) {
+	cfg := OTLPSinkConfig{
+		Endpoint: firstEnv(EnvKeyOTLPEndpoint, "OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "OTEL_EXPORTER_OTLP_ENDPOINT"),
+		Protocol: strings.ToLower(strings.TrimSpace(firstEnv(EnvKeyOTLPProtocol, "OTEL_EXPORTER_OTLP_LOGS_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"))),
+		Resource: OTLPResource(),
+	}
+	headers, err := ParseOTLPHeaders(firstEnv(EnvKeyOTLPHeaders, "OTEL_EXPORTER_OTLP_LOGS_HEADERS", "OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Headers = headers
+	if v := os.Getenv(EnvKeyOTLPTimeout); v != "" {
+		if cfg.Timeout, err = time.ParseDuration(v); err != nil {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyOTLPTimeout, v)
+		}
+	}
+	if v := os.Getenv(EnvKeyOTLPBatchTimeout); v != "" {
+		if cfg.BatchTimeout, err = time.ParseDuration(v); err != nil {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyOTLPBatchTimeout, v)
+		}
+	}
+	for key, dst := range map[string]*int{
+		EnvKeyOTLPBatchSize:  &cfg.BatchSize,
+		EnvKeyOTLPQueueSize:  &cfg.QueueSize,
+		EnvKeyOTLPMaxRetries: &cfg.MaxRetries,
+	} {
+		if v := os.Getenv(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return cfg, fmt.Errorf("invalid %s [%s]", key, v)
+			}
+			*dst = n
+		}
+	}
+	return cfg, nil
+}
+
+// ParseOTLPHeaders parses "k1=v1,k2=v2" (values may be URL-encoded, as in OTEL_EXPORTER_OTLP_HEADERS).
+func ParseOTLPHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid OTLP header [%s]", pair)
+		}
+		if dv, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = dv
+		}
+		headers[strings.TrimSpace(k)] = v
+	}
+	return headers, nil
+}
+
+func firstEnv(keys ...string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// OTLPSink exports entries as OTLP LogRecords. Records are batched by a background
+// goroutine and exported over OTLP/HTTP (protobuf) or OTLP/gRPC.
+type OTLPSink struct {
+	cfg    OTLPSinkConfig
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	queue   chan *otlpLogRecord
+	flushCh chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	closeOnce sync.Once
+	closeMu   sync.RWMutex
+	closed    bool
+
+	exported atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+	dropWarn warnLimiter
+	failWarn warnLimiter
+}
+
+// NewOTLPSink validates cfg and starts the batching goroutine.
+func NewOTLPSink(cfg OTLPSinkConfig) (*OTLPSink, error) {
+	if cfg.Protocol == "" {
+		cfg.Protocol = OTLPProtocolHTTP
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.BatchTimeout == 0 {
+		cfg.BatchTimeout = time.Second
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 2048
+	}
+	endpoint := cfg.Endpoint
+	switch cfg.Protocol {
+	case OTLPProtocolHTTP:
+		if endpoint == "" {
+			endpoint = "http://localhost:4318"
+		}
+	case OTLPProtocolGRPC:
+		if endpoint == "" {
+			endpoint = "http://localhost:4317"
+		}
+	default:
+		return nil, fmt.Errorf("invalid OTLP protocol [%s], valid values=[http/protobuf, grpc]", cfg.Protocol)
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint [%s]", cfg.Endpoint)
+	}
+	if cfg.Protocol == OTLPProtocolGRPC {
+		u.Path = otlpGRPCPath
+	} else if u.Path == "" || u.Path == "/" {
+		u.Path = otlpHTTPPath
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{}
+		if cfg.Protocol == OTLPProtocolGRPC {
+			// gRPC requires HTTP/2, also on plaintext (h2c) collector endpoints.
+			var protocols http.Protocols
+			protocols.SetHTTP2(true)
+			protocols.SetUnencryptedHTTP2(true)
+			client.Transport = &http.Transport{Protocols: &protocols}
+		}
+	}
+	s := &OTLPSink{
+		cfg:     cfg,
+		url:     u.String(),
+		client:  client,
+		now:     time.Now,
+		queue:   make(chan *otlpLogRecord, cfg.QueueSize),
+		flushCh: make(chan chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write queues e for export. It never blocks; a full queue drops the record and counts it
+// (see Stats), with an engine warning at most once per minute.
+func (s *OTLPSink) Write(e *Entry) error {
+	rec := &otlpLogRecord{
+		timeUnixNano:   uint64(e.timeOr(s.now).UnixNano()),
+		severityNumber: OTLPSeverity(e.Level),
+		severityText:   strings.ToUpper(e.Level),
+		body:           toString(e.Data["message"]),
+		attributes:     make(map[string]interface{}, len(e.Data)+1),
+		traceID:        decodeTraceBytes(e.TraceID, 16),
+		spanID:         decodeTraceBytes(e.SpanID, 8),
+	}
+	for k, v := range e.Data {
+		if k != "message" && v != nil && v != "" {
+			rec.attributes[k] = v
+		}
+	}
+	if e.LoggerName != "" {
+		rec.attributes["logger.name"] = e.LoggerName
+	}
+
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		return errors.New("otlp sink is closed")
+	}
+	select {
+	case s.queue <- rec:
+		return nil
+	default:
+		s.dropped.Add(1)
+		if n := s.dropWarn.add(1); n > 0 {
+			log.RootLogger().Warnf("CustomLog OTLP export queue is full, %d records dropped since the last warning", n)
+		}
+		return nil
+	}
+}
+
+// Flush exports all queued records and waits for completion.
+func (s *OTLPSink) Flush() {
+	ack := make(chan struct{})
+	select {
+	case s.flushCh <- ack:
+		<-ack
+	case <-s.done:
+	}
+}
+
+// Close exports the remaining records and stops the batching goroutine. A batch waiting
+// to be retried gets one last attempt without waiting for its backoff.
+func (s *OTLPSink) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeMu.Lock()
+		s.closed = true
+		close(s.stop)
+		close(s.queue)
+		s.closeMu.Unlock()
+		<-s.done
+	})
+	return nil
+}
+
+// Stats returns the number of exported, dropped (queue full) and failed (export error) records.
+func (s *OTLPSink) Stats() (exported, dropped, failed int64) {
+	return s.exported.Load(), s.dropped.Load(), s.failed.Load()
+}
+
+func (s *OTLPSink) run() {
+	defer close(s.done)
+	batch := make([]*otlpLogRecord, 0, s.cfg.BatchSize)
+	timer := time.NewTimer(s.cfg.BatchTimeout)
+	defer timer.Stop()
+	export := func() {
+		if len(batch) > 0 {
+			s.exportBatch(batch)
+			batch = make([]*otlpLogRecord, 0, s.cfg.BatchSize)
+		}
+	}
+	for {
+		select {
+		case rec, ok := <-s.queue:
+			if !ok {
+				export()
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) >= s.cfg.BatchSize {
+				export()
+			}
+		case <-timer.C:
+			export()
+			timer.Reset(s.cfg.BatchTimeout)
+		case ack := <-s.flushCh:
+			for drained := false; !drained; {
+				select {
+				case rec, ok := <-s.queue:
+					if !ok {
+						drained = true
+						break
+					}
+					batch = append(batch, rec)
+					if len(batch) >= s.cfg.BatchSize {
+						export()
+					}
+				default:
+					drained = true
+				}
+			}
+			export()
+			close(ack)
+		}
+	}
+}
+
+func (s *OTLPSink) exportBatch(batch []*otlpLogRecord) {
+	payload := encodeExportLogsRequest(s.cfg.Resource, otlpScopeName, "", batch)
+	backoff := 500 * time.Millisecond
+	stopping := false
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.send(payload)
+		if err == nil {
+			s.exported.Add(int64(len(batch)))
+			return
+		}
+		if !retryable || attempt >= s.cfg.MaxRetries || stopping {
+			s.failed.Add(int64(len(batch)))
+			if n := s.failWarn.add(int64(len(batch))); n > 0 {
+				log.RootLogger().Warnf("CustomLog OTLP export failed, %d records dropped since the last warning: %v", n, err)
+			}
+			return
+		}
+		stopping = !s.wait(backoff)
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// wait sleeps for d and returns false when Close interrupts it.
+func (s *OTLPSink) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-s.stop:
+		return false
+	}
+}
+
+// send performs one export request and reports whether a failure may be retried.
+func (s *OTLPSink) send(payload []byte) (retryable bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	body := payload
+	contentType := "application/x-protobuf"
+	if s.cfg.Protocol == OTLPProtocolGRPC {
+		// Length-prefixed message: compressed flag (0) + big-endian uint32 length.
+		body = make([]byte, 5, 5+len(payload))
+		binary.BigEndian.PutUint32(body[1:], uint32(len(payload)))
+		body = append(body, payload...)
+		contentType = "application/grpc"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.cfg.Protocol == OTLPProtocolGRPC {
+		req.Header.Set("TE", "trailers")
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if s.cfg.Protocol == OTLPProtocolGRPC {
+		if resp.StatusCode != http.StatusOK {
+			return isRetryableHTTPStatus(resp.StatusCode), fmt.Errorf("OTLP/gRPC HTTP status %d", resp.StatusCode)
+		}
+		status := resp.Trailer.Get("Grpc-Status")
+		if status == "" {
+			status = resp.Header.Get("Grpc-Status")
+		}
+		if status == "" || status == "0" {
+			return false, nil
+		}
+		code, _ := strconv.Atoi(status)
+		return isRetryableGRPCCode(code), fmt.Errorf("OTLP/gRPC status %s: %s", status, resp.Trailer.Get("Grpc-Message"))
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return isRetryableHTTPStatus(resp.StatusCode), fmt.Errorf("OTLP/HTTP status %d", resp.StatusCode)
+}
+
+// isRetryableHTTPStatus follows the OTLP/HTTP specification for retryable responses.
+func isRetryableHTTPStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetryableGRPCCode follows the OTLP/gRPC specification for retryable status codes.
+func isRetryableGRPCCode(code int) bool {
+	switch code {
+	case 1, 4, 8, 10, 11, 14, 15: // CANCELLED, DEADLINE_EXCEEDED, RESOURCE_EXHAUSTED, ABORTED, OUT_OF_RANGE, UNAVAILABLE, DATA_LOSS
+		return true
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package logutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var otlpTestEntry = &Entry{
+	Level:      "ERROR",
+	LoggerName: "flogo.test",
+	TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+	SpanID:     "00f067aa0ba902b7",
+	Data: map[string]interface{}{
+		"applicationName": "OrderApp",
+		"message":         "Payment failed",
+		"retries":         3,
+	},
+}
+
+func TestEncodeAnyValue(t *testing.T) {
+	var p protoBuf
+	encodeAnyValue(&p, "hi")
+	assert.Equal(t, []byte{0x0a, 0x02, 'h', 'i'}, p.b)
+
+	p = protoBuf{}
+	encodeAnyValue(&p, true)
+	assert.Equal(t, []byte{0x10, 0x01}, p.b)
+
+	p = protoBuf{}
+	encodeAnyValue(&p, 300)
+	assert.Equal(t, []byte{0x18, 0xac, 0x02}, p.b)
+}
+
+func TestOTLPSeverity(t *testing.T) {
+	assert.Equal(t, 5, OTLPSeverity("DEBUG"))
+	assert.Equal(t, 9, OTLPSeverity("info"))
+	assert.Equal(t, 13, OTLPSeverity("WARN"))
+	assert.Equal(t, 17, OTLPSeverity("ERROR"))
+}
+
+func TestParseOTLPHeaders(t *testing.T) {
+	h, err := ParseOTLPHeaders("Authorization=Bearer%20abc, x-tenant = t1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer abc", "x-tenant": "t1"}, h)
+
+	_, err = ParseOTLPHeaders("novalue")
+	assert.Error(t, err)
+}
+
+func assertOTLPPayload(t *testing.T, payload []byte) {
+	traceID, _ := hex.DecodeString(otlpTestEntry.TraceID)
+	spanID, _ := hex.DecodeString(otlpTestEntry.SpanID)
+	assert.True(t, bytes.Contains(payload, traceID), "trace id")
+	assert.True(t, bytes.Contains(payload, spanID), "span id")
+	for _, s := range []string{"Payment failed", "applicationName", "OrderApp", "service.name", "ERROR", otlpScopeName} {
+		assert.True(t, bytes.Contains(payload, []byte(s)), s)
+	}
+}
+
+func TestOTLPSinkHTTP(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		// First attempt fails with a retryable status to exercise the retry path.
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer srv.Close()
+
+	s, err := NewOTLPSink(OTLPSinkConfig{
+		Endpoint:   srv.URL,
+		Headers:    map[string]string{"X-Api-Key": "secret"},
+		Resource:   map[string]interface{}{"service.name": "OrderApp"},
+		MaxRetries: 2,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(otlpTestEntry))
+	require.NoError(t, s.Close())
+
+	assertOTLPPayload(t, <-received)
+	exported, dropped, failed := s.Stats()
+	assert.Equal(t, [3]int64{1, 0, 0}, [3]int64{exported, dropped, failed})
+}
+
+func TestOTLPSinkCloseDuringBackoff(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// 10 retries back off for minutes; Close gives the batch one last attempt instead
+	s, err := NewOTLPSink(OTLPSinkConfig{Endpoint: srv.URL, MaxRetries: 10})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(otlpTestEntry))
+	start := time.Now()
+	require.NoError(t, s.Close())
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	exported, dropped, failed := s.Stats()
+	assert.Equal(t, [3]int64{0, 0, 1}, [3]int64{exported, dropped, failed})
+	assert.LessOrEqual(t, calls.Load(), int32(3))
+}
+
+func TestOTLPSinkGRPC(t *testing.T) {
+	received := make(chan []byte, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor)
+		assert.Equal(t, otlpGRPCPath, r.URL.Path)
+		assert.Equal(t, "application/grpc", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		require.GreaterOrEqual(t, len(body), 5)
+		assert.Equal(t, uint32(len(body)-5), binary.BigEndian.Uint32(body[1:5]))
+		received <- body[5:]
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Grpc-Status", "0")
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	srv := &http.Server{Handler: handler, Protocols: &protocols}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	s, err := NewOTLPSink(OTLPSinkConfig{
+		Endpoint:     ln.Addr().String(),
+		Protocol:     OTLPProtocolGRPC,
+		Resource:     map[string]interface{}{"service.name": "OrderApp"},
+		BatchTimeout: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Write(otlpTestEntry))
+	s.Flush()
+
+	select {
+	case payload := <-received:
+		assertOTLPPayload(t, payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no export received")
+	}
+	exported, _, failed := s.Stats()
+	assert.Equal(t, int64(1), exported)
+	assert.Equal(t, int64(0), failed)
+}
@@ -0,0 +1,484 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// EnvKeyPattern sets the layout of the "pattern" format.
+const EnvKeyPattern = "FLOGO_CUSTOMLOG_PATTERN"
+
+// DefaultPattern reproduces the "text" format.
+const DefaultPattern = "%d %-5p [%c] - %kvp"
+
+// Named date patterns accepted by %d{...}, as in log4j.
+var namedDatePatterns = map[string]string{
+	"DEFAULT":  "yyyy-MM-dd HH:mm:ss,SSS",
+	"ISO8601":  "yyyy-MM-dd'T'HH:mm:ss,SSS",
+	"ABSOLUTE": "HH:mm:ss,SSS",
+	"DATE":     "dd MMM yyyy HH:mm:ss,SSS",
+	"COMPACT":  "yyyyMMddHHmmssSSS",
+}
+
+// patternRecord is the input of the compiled converters.
+type patternRecord struct {
+	data       map[string]interface{}
+	sections   map[string]string
+	levelUpper string
+	loggerName string
+	now        time.Time
+}
+
+// patternElement is a literal or a conversion with its format modifier.
+type patternElement struct {
+	literal string
+	convert func(r *patternRecord) string
+
+	minWidth    int
+	maxWidth    int
+	leftAlign   bool
+	truncateEnd bool
+}
+
+// Pattern is a compiled pattern layout. It is immutable and safe for concurrent use.
+//
+// Conversion words:
+//
+//	%d{pattern}{zone}  date (Java SimpleDateFormat letters or DEFAULT, ISO8601, ABSOLUTE, DATE, COMPACT, UNIX, UNIX_MILLIS)
+//	                   in the zone of the TimestampConfig unless given; %d alone is the record timestamp
+//	%p, %level         log level
+//	%c{n}, %logger{n}  logger name; n > 0 keeps the last n segments, n < 0 drops the first -n
+//	%m, %msg, %message the message key
+//	%X{key}, %mdc{key} a log data key; without a key, all log data as {k=v, ...}
+//	%kvp               all log data as key="value" pairs, as in the text format
+//	%n                 newline
+//	%%                 percent sign
+//
+// Format modifiers follow '%': "-" left-aligns, a number sets the minimum width, and
+// ".n" truncates to n characters from the beginning (".-n" from the end), e.g. %-5p, %.30c.
+type Pattern struct {
+	source   string
+	elements []patternElement
+}
+
+// CompilePattern parses a pattern layout.
+func CompilePattern(pattern string) (*Pattern, error) {
+	p := &Pattern{source: pattern}
+	var lit strings.Builder
+	flush := func() {
+		if lit.Len() > 0 {
+			p.elements = append(p.elements, patternElement{literal: lit.String()})
+			lit.Reset()
+		}
+	}
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		if c != '%' {
+			lit.WriteByte(c)
+			i++
+			continue
+		}
+		i++
+		if i >= len(pattern) {
+			return nil, fmt.Errorf("pattern ends with '%%'")
+		}
+		if pattern[i] == '%' {
+			lit.WriteByte('%')
+			i++
+			continue
+		}
+		var el patternElement
+		if pattern[i] == '-' {
+			el.leftAlign = true
+			i++
+		}
+		el.minWidth, i = patternNumber(pattern, i)
+		if i < len(pattern) && pattern[i] == '.' {
+			i++
+			if i < len(pattern) && pattern[i] == '-' {
+				el.truncateEnd = true
+				i++
+			}
+			start := i
+			if el.maxWidth, i = patternNumber(pattern, i); i == start || el.maxWidth == 0 {
+				return nil, fmt.Errorf("invalid maximum width at offset %d", start)
+			}
+		}
+		start := i
+		for i < len(pattern) && isASCIILetter(pattern[i]) {
+			i++
+		}
+		word := pattern[start:i]
+		var args []string
+		for i < len(pattern) && pattern[i] == '{' {
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '{' after %%%s", word)
+			}
+			args = append(args, pattern[i+1:i+end])
+			i += end + 1
+		}
+		convert, err := patternConverter(word, args)
+		if err != nil {
+			return nil, err
+		}
+		if word == "n" {
+			lit.WriteByte('\n')
+			continue
+		}
+		flush()
+		el.convert = convert
+		p.elements = append(p.elements, el)
+	}
+	flush()
+	return p, nil
+}
+
+// String returns the source pattern.
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Format writes one record with the pattern.
+func (p *Pattern) Format(logData map[string]interface{}, level string, loggerName string, now time.Time) string {
+	return p.format(logData, nil, level, loggerName, now)
+}
+
+// format writes one record whose keys are grouped by sections (see Entry.Sections).
+func (p *Pattern) format(logData map[string]interface{}, sections map[string]string, level string, loggerName string, now time.Time) string {
+	r := &patternRecord{data: logData, sections: sections, levelUpper: strings.ToUpper(level), loggerName: loggerName, now: now}
+	var b strings.Builder
+	b.Grow(256)
+	for i := range p.elements {
+		el := &p.elements[i]
+		if el.convert == nil {
+			b.WriteString(el.literal)
+			continue
+		}
+		el.write(&b, el.convert(r))
+	}
+	return b.String()
+}
+
+func (el *patternElement) write(b *strings.Builder, s string) {
+	n := utf8.RuneCountInString(s)
+	if el.maxWidth > 0 && n > el.maxWidth {
+		runes := []rune(s)
+		if el.truncateEnd {
+			s = string(runes[:el.maxWidth])
+		} else {
+			s = string(runes[n-el.maxWidth:])
+		}
+		n = el.maxWidth
+	}
+	pad := el.minWidth - n
+	if pad > 0 && !el.leftAlign {
+		b.WriteString(strings.Repeat(" ", pad))
+	}
+	b.WriteString(s)
+	if pad > 0 && el.leftAlign {
+		b.WriteString(strings.Repeat(" ", pad))
+	}
+}
+
+func patternConverter(word string, args []string) (func(r *patternRecord) string, error) {
+	arg := func(i int) string {
+		if i < len(args) {
+			return strings.TrimSpace(args[i])
+		}
+		return ""
+	}
+	switch word {
+	case "d", "date":
+		return dateConverter(arg(0), arg(1))
+	case "p", "level":
+		return func(r *patternRecord) string { return r.levelUpper }, nil
+	case "c", "logger":
+		return loggerConverter(arg(0))
+	case "m", "msg", "message":
+		return func(r *patternRecord) string { return toString(r.data["message"]) }, nil
+	case "X", "mdc":
+		if key := arg(0); key != "" {
+			return func(r *patternRecord) string { return toString(r.data[key]) }, nil
+		}
+		return func(r *patternRecord) string { return mdcString(r.data) }, nil
+	case "kvp":
+		return func(r *patternRecord) string {
+			return textPairs(r.data, defaultTextConfig(), defaultKeyNames(r.sections))
+		}, nil
+	case "n":
+		return nil, nil
+	case "":
+		return nil, fmt.Errorf("missing conversion word after '%%'")
+	}
+	return nil, fmt.Errorf("unknown conversion word %%%s", word)
+}
+
+func loggerConverter(arg string) (func(r *patternRecord) string, error) {
+	if arg == "" {
+		return func(r *patternRecord) string { return r.loggerName }, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n == 0 {
+		return nil, fmt.Errorf("invalid logger precision {%s}", arg)
+	}
+	return func(r *patternRecord) string {
+		parts := strings.Split(r.loggerName, ".")
+		if n > 0 {
+			if n < len(parts) {
+				parts = parts[len(parts)-n:]
+			}
+		} else if -n < len(parts) {
+			parts = parts[-n:]
+		} else {
+			parts = parts[len(parts)-1:]
+		}
+		return strings.Join(parts, ".")
+	}, nil
+}
+
+func dateConverter(pattern, zone string) (func(r *patternRecord) string, error) {
+	var loc *time.Location
+	if zone != "" {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return nil, fmt.Errorf("invalid time zone {%s}", zone)
+		}
+	}
+	inZone := func(t time.Time) time.Time {
+		if loc != nil {
+			return t.In(loc)
+		}
+		return t.In(defaultTimestampConfig().location())
+	}
+	switch pattern {
+	case "UNIX":
+		return func(r *patternRecord) string { return strconv.FormatInt(r.now.Unix(), 10) }, nil
+	case "UNIX_MILLIS":
+		return func(r *patternRecord) string { return strconv.FormatInt(r.now.UnixMilli(), 10) }, nil
+	case "":
+		if loc == nil {
+			return func(r *patternRecord) string { return defaultTimestampConfig().formatText(r.now) }, nil
+		}
+		pattern = "DEFAULT"
+	}
+	if named, ok := namedDatePatterns[pattern]; ok {
+		pattern = named
+	}
+	tokens, err := compileDatePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(r *patternRecord) string { return formatDate(inZone(r.now), tokens) }, nil
+}
+
+// dateToken is a run of one pattern letter, or a literal when letter is 0.
+type dateToken struct {
+	letter  byte
+	count   int
+	literal string
+}
+
+// compileDatePattern parses the Java SimpleDateFormat letters y M d H h m s S a E Z X z;
+// text between single quotes is literal and two single quotes write one.
+func compileDatePattern(pattern string) ([]dateToken, error) {
+	var tokens []dateToken
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c == '\'':
+			end := i + 1
+			var lit strings.Builder
+			for end < len(pattern) {
+				if pattern[end] == '\'' {
+					if end+1 < len(pattern) && pattern[end+1] == '\'' {
+						lit.WriteByte('\'')
+						end += 2
+						continue
+					}
+					break
+				}
+				lit.WriteByte(pattern[end])
+				end++
+			}
+			if i+1 == end && end < len(pattern) {
+				lit.WriteByte('\'')
+			} else if end >= len(pattern) {
+				return nil, fmt.Errorf("unclosed quote in date pattern {%s}", pattern)
+			}
+			tokens = append(tokens, dateToken{literal: lit.String()})
+			i = end + 1
+		case isASCIILetter(c):
+			if !strings.ContainsRune("yMdHhmsSaEZXz", rune(c)) {
+				return nil, fmt.Errorf("unsupported letter '%c' in date pattern {%s}", c, pattern)
+			}
+			n := 1
+			for i+n < len(pattern) && pattern[i+n] == c {
+				n++
+			}
+			tokens = append(tokens, dateToken{letter: c, count: n})
+			i += n
+		default:
+			tokens = append(tokens, dateToken{literal: string(c)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func formatDate(t time.Time, tokens []dateToken) string {
+	var b strings.Builder
+	b.Grow(32)
+	pad := func(v, width int) {
+		s := strconv.Itoa(v)
+		for i := len(s); i < width; i++ {
+			b.WriteByte('0')
+		}
+		b.WriteString(s)
+	}
+	for _, tok := range tokens {
+		switch tok.letter {
+		case 0:
+			b.WriteString(tok.literal)
+		case 'y':
+			if tok.count == 2 {
+				pad(t.Year()%100, 2)
+			} else {
+				pad(t.Year(), tok.count)
+			}
+		case 'M':
+			switch {
+			case tok.count >= 4:
+				b.WriteString(t.Month().String())
+			case tok.count == 3:
+				b.WriteString(t.Month().String()[:3])
+			default:
+				pad(int(t.Month()), tok.count)
+			}
+		case 'd':
+			pad(t.Day(), tok.count)
+		case 'H':
+			pad(t.Hour(), tok.count)
+		case 'h':
+			h := t.Hour() % 12
+			if h == 0 {
+				h = 12
+			}
+			pad(h, tok.count)
+		case 'm':
+			pad(t.Minute(), tok.count)
+		case 's':
+			pad(t.Second(), tok.count)
+		case 'S':
+			frac := fmt.Sprintf("%09d", t.Nanosecond())
+			if tok.count <= 9 {
+				b.WriteString(frac[:tok.count])
+			} else {
+				b.WriteString(frac + strings.Repeat("0", tok.count-9))
+			}
+		case 'a':
+			b.WriteString(t.Format("PM"))
+		case 'E':
+			if tok.count >= 4 {
+				b.WriteString(t.Weekday().String())
+			} else {
+				b.WriteString(t.Weekday().String()[:3])
+			}
+		case 'Z':
+			b.WriteString(t.Format("-0700"))
+		case 'X':
+			switch tok.count {
+			case 1:
+				b.WriteString(t.Format("Z07"))
+			case 2:
+				b.WriteString(t.Format("Z0700"))
+			default:
+				b.WriteString(t.Format("Z07:00"))
+			}
+		case 'z':
+			b.WriteString(t.Format("MST"))
+		}
+	}
+	return b.String()
+}
+
+// mdcString writes all log data as {k1=v1, k2=v2}, like log4j's %X without a key.
+func mdcString(logData map[string]interface{}) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	first := true
+	for _, k := range OrderedKeys(logData) {
+		s := toString(logData[k])
+		if s == "" {
+			continue
+		}
+		if !first {
+			b.WriteString(", ")
+		}
+		first = false
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(s)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func patternNumber(s string, i int) (int, int) {
+	n := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		n = n*10 + int(s[i]-'0')
+		i++
+	}
+	return n, i
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+var patternCache sync.Map // source -> *Pattern
+
+// cachedPattern compiles pattern once per distinct source.
+func cachedPattern(pattern string) (*Pattern, error) {
+	if p, ok := patternCache.Load(pattern); ok {
+		return p.(*Pattern), nil
+	}
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := patternCache.LoadOrStore(pattern, p)
+	return actual.(*Pattern), nil
+}
+
+// PatternFromEnv returns FLOGO_CUSTOMLOG_PATTERN, or DefaultPattern when it is not set.
+func PatternFromEnv() string {
+	if p := os.Getenv(EnvKeyPattern); strings.TrimSpace(p) != "" {
+		return p
+	}
+	return DefaultPattern
+}
+
+var (
+	defaultPatternOnce sync.Once
+	defaultPatternVal  *Pattern
+)
+
+// defaultLayout is the compiled FLOGO_CUSTOMLOG_PATTERN. An invalid pattern (reported by
+// ActivityConfig.Validate) falls back to DefaultPattern.
+func defaultLayout() *Pattern {
+	defaultPatternOnce.Do(func() {
+		p, err := cachedPattern(PatternFromEnv())
+		if err != nil {
+			p, _ = cachedPattern(DefaultPattern)
+		}
+		defaultPatternVal = p
+	})
+	return defaultPatternVal
+}
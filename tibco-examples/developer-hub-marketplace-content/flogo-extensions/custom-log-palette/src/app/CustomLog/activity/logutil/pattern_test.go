@@ -0,0 +1,76 @@
+package logutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func patternTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"applicationName": "Orders",
+		"message":         "Order accepted",
+		"orderId":         "o-1",
+		"empty":           "",
+	}
+}
+
+func TestDefaultPatternMatchesText(t *testing.T) {
+	p, err := CompilePattern(DefaultPattern)
+	require.NoError(t, err)
+	now := time.Date(2026, 2, 13, 16, 53, 47, 498000000, time.Local)
+	data := patternTestData()
+	assert.Equal(t, formatText(data, "INFO", "flogo.test", now, TextConfig{}, defaultKeyNames(nil)), p.Format(data, "info", "flogo.test", now))
+}
+
+func TestPatternFormat(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 5, 7, 123456789, time.UTC)
+	data := patternTestData()
+	logger := "flogo.CustomLog.app.flow.activity"
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"%d{yyyy-MM-dd HH:mm:ss.SSS} %-5p %c{2} - %m%n", "2026-03-01 09:05:07.123 INFO  flow.activity - Order accepted\n"},
+		{"[%5p] [%.8c] [%.-5m]", "[ INFO] [activity] [Order]"},
+		{"%c{-3}|%logger{1}|%-6level|", "flow.activity|activity|INFO  |"},
+		{"%X{orderId} %X{missing}%mdc", "o-1 {applicationName=Orders, message=Order accepted, orderId=o-1}"},
+		{"%d{ISO8601} %d{ABSOLUTE} %d{UNIX}", "2026-03-01T09:05:07,123 09:05:07,123 1772355907"},
+		{"%d{dd MMM yyyy h:mm a EEE}", "01 Mar 2026 9:05 AM Sun"},
+		{"%d{yyyyMMdd'T'HHmmss''SSSSSS XXX}", "20260301T090507'123456 Z"},
+		{"%d{HH:mm}{America/New_York} 100%%", "04:05 100%"},
+		{"%kvp", `a_applicationName="Orders", a_message="Order accepted", a_orderId="o-1"`},
+	}
+	for _, tt := range tests {
+		p, err := CompilePattern(tt.pattern)
+		require.NoError(t, err, tt.pattern)
+		assert.Equal(t, tt.want, p.Format(data, "INFO", logger, now), tt.pattern)
+		assert.Equal(t, tt.pattern, p.String())
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	for _, pattern := range []string{
+		"%", "%q", "%-5", "%.0m", "%X{key", "%c{x}", "%d{yyyy-QQ}", "%d{'T}", "%d{HH}{Mars/Olympus}",
+	} {
+		_, err := CompilePattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestPatternFormatAndValidation(t *testing.T) {
+	p, err := cachedPattern("%p %m")
+	require.NoError(t, err)
+	again, _ := cachedPattern("%p %m")
+	assert.Same(t, p, again)
+
+	assert.True(t, IsLogFormat("Pattern"))
+	assert.NoError(t, ValidatePattern(DefaultPattern))
+	assertErrorCode(t, ValidatePattern("%d{yyyy} %z"), ErrCodeInvalidPattern)
+
+	t.Setenv(EnvKeyPattern, "%p %bogus")
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidPattern)
+}
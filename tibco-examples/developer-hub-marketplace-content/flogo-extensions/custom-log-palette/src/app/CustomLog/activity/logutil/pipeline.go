@@ -0,0 +1,303 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/project-flogo/core/engine"
+	"github.com/project-flogo/core/support/log"
+)
+
+// Environment variables configuring the asynchronous log pipeline.
+const (
+	EnvKeyAsync          = "FLOGO_CUSTOMLOG_ASYNC"
+	EnvKeyAsyncQueueSize = "FLOGO_CUSTOMLOG_ASYNC_QUEUE_SIZE"
+	EnvKeyAsyncWorkers   = "FLOGO_CUSTOMLOG_ASYNC_WORKERS"
+	EnvKeyAsyncPolicy    = "FLOGO_CUSTOMLOG_ASYNC_POLICY"
+	EnvKeyAsyncMinLevel  = "FLOGO_CUSTOMLOG_ASYNC_MIN_LEVEL"
+)
+
+// Policies applied by AsyncPipeline when its queue is full.
+const (
+	PolicyBlock           = "block"
+	PolicyDropNewest      = "drop-newest"
+	PolicyDropOldest      = "drop-oldest"
+	PolicyDropBelowLevel  = "drop-below-level"
+	defaultAsyncQueueSize = 1024
+)
+
+func init() {
+	// Drain the pipeline and close sinks when the engine stops, so buffered
+	// records and file/OTLP sinks are flushed on shutdown.
+	engine.LifeCycle(&shutdownService{})
+}
+
+// AsyncConfig configures an AsyncPipeline.
+type AsyncConfig struct {
+	// QueueSize bounds the records waiting to be written.
+	QueueSize int
+	// Workers is the number of writer goroutines. With more than one worker,
+	// records may reach the sinks out of order.
+	Workers int
+	// Policy is one of PolicyBlock, PolicyDropNewest, PolicyDropOldest or PolicyDropBelowLevel.
+	Policy string
+	// MinLevel is the lowest level that still blocks under PolicyDropBelowLevel;
+	// less severe records are dropped when the queue is full.
+	MinLevel string
+}
+
+// AsyncConfigFromEnv reads the pipeline configuration from FLOGO_CUSTOMLOG_ASYNC_* variables.
+// enabled reports whether FLOGO_CUSTOMLOG_ASYNC is true.
+func AsyncConfigFromEnv() (cfg AsyncConfig, enabled bool, err error) {
+	if v := os.Getenv(EnvKeyAsync); v != "" {
+		if enabled, err = strconv.ParseBool(v); err != nil {
+			return cfg, false, fmt.Errorf("invalid %s [%s]", EnvKeyAsync, v)
+		}
+	}
+	cfg.Policy = strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeyAsyncPolicy)))
+	if cfg.Policy != "" && !isAsyncPolicy(cfg.Policy) {
+		return cfg, false, fmt.Errorf("invalid %s [%s], valid values=[block, drop-newest, drop-oldest, drop-below-level]", EnvKeyAsyncPolicy, cfg.Policy)
+	}
+	cfg.MinLevel = strings.ToUpper(strings.TrimSpace(os.Getenv(EnvKeyAsyncMinLevel)))
+	if cfg.MinLevel != "" && LevelRank(cfg.MinLevel) < 0 {
+		return cfg, false, fmt.Errorf("invalid %s [%s], valid values=[DEBUG, INFO, WARN, ERROR]", EnvKeyAsyncMinLevel, cfg.MinLevel)
+	}
+	if v := os.Getenv(EnvKeyAsyncQueueSize); v != "" {
+		if cfg.QueueSize, err = strconv.Atoi(v); err != nil || cfg.QueueSize <= 0 {
+			return cfg, false, fmt.Errorf("invalid %s [%s]", EnvKeyAsyncQueueSize, v)
+		}
+	}
+	if v := os.Getenv(EnvKeyAsyncWorkers); v != "" {
+		if cfg.Workers, err = strconv.Atoi(v); err != nil || cfg.Workers <= 0 {
+			return cfg, false, fmt.Errorf("invalid %s [%s]", EnvKeyAsyncWorkers, v)
+		}
+	}
+	return cfg, enabled, nil
+}
+
+func isAsyncPolicy(policy string) bool {
+	switch policy {
+	case PolicyBlock, PolicyDropNewest, PolicyDropOldest, PolicyDropBelowLevel:
+		return true
+	}
+	return false
+}
+
+// AsyncStats is a snapshot of the pipeline counters.
+type AsyncStats struct {
+	Enqueued int64
+	Written  int64
+	Dropped  int64
+	Failed   int64
+}
+
+type asyncItem struct {
+	sinks []Sink
+	entry *Entry
+}
+
+// AsyncPipeline formats and writes entries on a pool of writer goroutines,
+// decoupling flow latency from slow sinks.
+type AsyncPipeline struct {
+	cfg      AsyncConfig
+	minLevel int
+	queue    chan asyncItem
+
+	closeMu sync.RWMutex
+	closed  bool
+	wg      sync.WaitGroup
+
+	enqueued atomic.Int64
+	written  atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+	dropWarn warnLimiter
+}
+
+// NewAsyncPipeline validates cfg and starts the writer goroutines.
+func NewAsyncPipeline(cfg AsyncConfig) (*AsyncPipeline, error) {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultAsyncQueueSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = PolicyBlock
+	}
+	if cfg.MinLevel == "" {
+		cfg.MinLevel = "WARN"
+	}
+	if !isAsyncPolicy(cfg.Policy) {
+		return nil, fmt.Errorf("invalid async policy [%s], valid values=[block, drop-newest, drop-oldest, drop-below-level]", cfg.Policy)
+	}
+	minLevel := LevelRank(cfg.MinLevel)
+	if minLevel < 0 {
+		return nil, fmt.Errorf("invalid async min level [%s], valid values=[DEBUG, INFO, WARN, ERROR]", cfg.MinLevel)
+	}
+	p := &AsyncPipeline{
+		cfg:      cfg,
+		minLevel: minLevel,
+		queue:    make(chan asyncItem, cfg.QueueSize),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p, nil
+}
+
+// Enqueue hands e to the writer goroutines, applying the full-queue policy.
+// It returns false if e was dropped. Drops are counted (see Stats), with an engine
+// warning at most once per minute.
+func (p *AsyncPipeline) Enqueue(sinks []Sink, e *Entry) bool {
+	item := asyncItem{sinks: sinks, entry: e}
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+	if p.closed {
+		p.drop()
+		return false
+	}
+	select {
+	case p.queue <- item:
+		p.enqueued.Add(1)
+		return true
+	default:
+	}
+	switch p.cfg.Policy {
+	case PolicyDropNewest:
+		p.drop()
+		return false
+	case PolicyDropBelowLevel:
+		if LevelRank(e.Level) < p.minLevel {
+			p.drop()
+			return false
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case p.queue <- item:
+				p.enqueued.Add(1)
+				return true
+			default:
+			}
+			select {
+			case <-p.queue:
+				p.drop()
+			default:
+			}
+		}
+	}
+	p.queue <- item
+	p.enqueued.Add(1)
+	return true
+}
+
+// drop counts a dropped record and warns about the drops since the last warning.
+func (p *AsyncPipeline) drop() {
+	p.dropped.Add(1)
+	if n := p.dropWarn.add(1); n > 0 {
+		log.RootLogger().Warnf("CustomLog async log queue full, %d records dropped since the last warning (policy %s)", n, p.cfg.Policy)
+	}
+}
+
+// Stats returns the current counters.
+func (p *AsyncPipeline) Stats() AsyncStats {
+	return AsyncStats{
+		Enqueued: p.enqueued.Load(),
+		Written:  p.written.Load(),
+		Dropped:  p.dropped.Load(),
+		Failed:   p.failed.Load(),
+	}
+}
+
+// Close stops accepting records and waits until the queue is drained.
+func (p *AsyncPipeline) Close() {
+	p.closeMu.Lock()
+	if p.closed {
+		p.closeMu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.closeMu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *AsyncPipeline) worker() {
+	defer p.wg.Done()
+	for item := range p.queue {
+		if err := WriteEntry(item.sinks, item.entry); err != nil {
+			p.failed.Add(1)
+			continue
+		}
+		p.written.Add(1)
+	}
+}
+
+var (
+	asyncMu       sync.Mutex
+	asyncPipeline *AsyncPipeline
+	asyncErr      error
+	asyncLoaded   bool
+)
+
+// DefaultAsyncPipeline returns the shared pipeline configured from the environment,
+// or nil when FLOGO_CUSTOMLOG_ASYNC is not enabled.
+func DefaultAsyncPipeline() (*AsyncPipeline, error) {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	if !asyncLoaded {
+		asyncLoaded = true
+		cfg, enabled, err := AsyncConfigFromEnv()
+		if err != nil {
+			asyncErr = err
+		} else if enabled {
+			asyncPipeline, asyncErr = NewAsyncPipeline(cfg)
+		}
+	}
+	return asyncPipeline, asyncErr
+}
+
+// Emit writes e to sinks, through the shared asynchronous pipeline when it is enabled.
+// In asynchronous mode formatting also happens on the writer goroutines, and sink
+// errors and records dropped by the full-queue policy are only reflected in the
+// pipeline counters.
+func Emit(sinks []Sink, e *Entry) error {
+	p, err := DefaultAsyncPipeline()
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return WriteEntry(sinks, e)
+	}
+	p.Enqueue(sinks, e)
+	return nil
+}
+
+// Shutdown drains the shared asynchronous pipeline and closes all opened sinks.
+func Shutdown() error {
+	asyncMu.Lock()
+	p := asyncPipeline
+	asyncPipeline, asyncErr, asyncLoaded = nil, nil, false
+	asyncMu.Unlock()
+	if p != nil {
+		p.Close()
+	}
+	return CloseSinks()
+}
+
+// shutdownService hooks Shutdown into the engine lifecycle.
+type shutdownService struct{}
+
+func (s *shutdownService) Start() error {
+	return nil
+}
+
+func (s *shutdownService) Stop() error {
+	return Shutdown()
+}
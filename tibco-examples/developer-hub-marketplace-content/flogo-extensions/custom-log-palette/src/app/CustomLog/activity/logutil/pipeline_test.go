@@ -0,0 +1,171 @@
+package logutil
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gateSink blocks every write until the gate is opened and records the written lines.
+type gateSink struct {
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	lines   []string
+}
+
+func newGateSink() *gateSink {
+	return &gateSink{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (s *gateSink) Write(e *Entry) error {
+	s.once.Do(func() { close(s.started) })
+	<-s.gate
+	s.mu.Lock()
+	s.lines = append(s.lines, e.Data["message"].(string))
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *gateSink) Close() error {
+	return nil
+}
+
+// fillPipeline occupies the single worker with "w" and fills the queue with "q1".."qN".
+func fillPipeline(t *testing.T, p *AsyncPipeline, sink *gateSink, n int) {
+	require.True(t, p.Enqueue([]Sink{sink}, testEntry("INFO", "w")))
+	<-sink.started
+	for i := 1; i <= n; i++ {
+		require.True(t, p.Enqueue([]Sink{sink}, testEntry("INFO", "q"+string(rune('0'+i)))))
+	}
+}
+
+func testEntry(level, msg string) *Entry {
+	return &Entry{Level: level, Data: map[string]interface{}{"message": msg}}
+}
+
+func TestAsyncPipelineDropNewest(t *testing.T) {
+	p, err := NewAsyncPipeline(AsyncConfig{QueueSize: 2, Policy: PolicyDropNewest})
+	require.NoError(t, err)
+	sink := newGateSink()
+	fillPipeline(t, p, sink, 2)
+
+	assert.False(t, p.Enqueue([]Sink{sink}, testEntry("ERROR", "new")))
+	close(sink.gate)
+	p.Close()
+
+	assert.Equal(t, []string{"w", "q1", "q2"}, sink.lines)
+	assert.Equal(t, AsyncStats{Enqueued: 3, Written: 3, Dropped: 1}, p.Stats())
+}
+
+func TestEmitDropsSilently(t *testing.T) {
+	t.Setenv(EnvKeyAsync, "true")
+	t.Setenv(EnvKeyAsyncQueueSize, "1")
+	t.Setenv(EnvKeyAsyncPolicy, PolicyDropNewest)
+	require.NoError(t, Shutdown())
+	t.Cleanup(func() { _ = Shutdown() })
+	p, err := DefaultAsyncPipeline()
+	require.NoError(t, err)
+	sink := newGateSink()
+	fillPipeline(t, p, sink, 1)
+
+	// a full queue is counted, not reported to every activity
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, Emit([]Sink{sink}, testEntry("INFO", "dropped")))
+	}
+	close(sink.gate)
+	p.Close()
+	assert.Equal(t, AsyncStats{Enqueued: 2, Written: 2, Dropped: 3}, p.Stats())
+}
+
+func TestAsyncPipelineDropOldest(t *testing.T) {
+	p, err := NewAsyncPipeline(AsyncConfig{QueueSize: 2, Policy: PolicyDropOldest})
+	require.NoError(t, err)
+	sink := newGateSink()
+	fillPipeline(t, p, sink, 2)
+
+	assert.True(t, p.Enqueue([]Sink{sink}, testEntry("INFO", "new")))
+	close(sink.gate)
+	p.Close()
+
+	assert.Equal(t, []string{"w", "q2", "new"}, sink.lines)
+	assert.Equal(t, AsyncStats{Enqueued: 4, Written: 3, Dropped: 1}, p.Stats())
+}
+
+func TestAsyncPipelineDropBelowLevel(t *testing.T) {
+	p, err := NewAsyncPipeline(AsyncConfig{QueueSize: 1, Policy: PolicyDropBelowLevel, MinLevel: "WARN"})
+	require.NoError(t, err)
+	sink := newGateSink()
+	fillPipeline(t, p, sink, 1)
+
+	assert.False(t, p.Enqueue([]Sink{sink}, testEntry("DEBUG", "debug")))
+
+	// A WARN record blocks until the worker frees a slot.
+	done := make(chan bool)
+	go func() { done <- p.Enqueue([]Sink{sink}, testEntry("WARN", "warn")) }()
+	close(sink.gate)
+	assert.True(t, <-done)
+	p.Close()
+
+	assert.Equal(t, []string{"w", "q1", "warn"}, sink.lines)
+	assert.Equal(t, AsyncStats{Enqueued: 3, Written: 3, Dropped: 1}, p.Stats())
+}
+
+func TestAsyncPipelineBlockConcurrent(t *testing.T) {
+	p, err := NewAsyncPipeline(AsyncConfig{QueueSize: 4, Workers: 3})
+	require.NoError(t, err)
+	sink := newGateSink()
+	close(sink.gate)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				p.Enqueue([]Sink{sink}, testEntry("INFO", "m"))
+			}
+		}()
+	}
+	wg.Wait()
+	p.Close()
+
+	assert.Len(t, sink.lines, 500)
+	assert.Equal(t, AsyncStats{Enqueued: 500, Written: 500}, p.Stats())
+}
+
+func TestNewAsyncPipelineInvalid(t *testing.T) {
+	_, err := NewAsyncPipeline(AsyncConfig{Policy: "drop-all"})
+	assert.Error(t, err)
+	_, err = NewAsyncPipeline(AsyncConfig{Policy: PolicyDropBelowLevel, MinLevel: "FATAL"})
+	assert.Error(t, err)
+}
+
+func TestAsyncConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyAsync, "true")
+	t.Setenv(EnvKeyAsyncQueueSize, "64")
+	t.Setenv(EnvKeyAsyncPolicy, "Drop-Oldest")
+	cfg, enabled, err := AsyncConfigFromEnv()
+	require.NoError(t, err)
+	assert.True(t, enabled)
+	assert.Equal(t, AsyncConfig{QueueSize: 64, Policy: PolicyDropOldest}, cfg)
+
+	for key, invalid := range map[string]string{
+		EnvKeyAsync:          "yes please",
+		EnvKeyAsyncQueueSize: "0",
+		EnvKeyAsyncWorkers:   "many",
+		EnvKeyAsyncPolicy:    "drop-all",
+		EnvKeyAsyncMinLevel:  "FATAL",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, invalid)
+			_, _, err := AsyncConfigFromEnv()
+			assert.Error(t, err)
+			cfg := &ActivityConfig{}
+			assertErrorCode(t, cfg.Validate(), ErrCodeInvalidAsync)
+		})
+	}
+}
@@ -0,0 +1,471 @@
+package logutil
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/data"
+	"github.com/project-flogo/core/engine"
+	"github.com/project-flogo/flow/instance"
+)
+
+// CustomFlowInfoKey is the flow variable holding the Header and contextParams stored by Set and Log.
+const CustomFlowInfoKey = "customFlowInfo"
+
+// RecordInput is the per-call input of a Custom Log activity.
+type RecordInput struct {
+	// Level is INFO, DEBUG, WARN or ERROR.
+	Level string
+	// Message is the default message; a non-empty "message" parameter replaces it.
+	Message string
+	// FlowInfo appends the flow instance ID, flow name and activity name to the message.
+	FlowInfo bool
+	// Header and ContextParams are logged directly (Set and Log).
+	Header        interface{}
+	ContextParams interface{}
+	// TriggerHeaders are raw trigger headers; correlation fields are extracted with the
+	// configured HeaderRules and overridden by Header and ContextParams (Set and Log).
+	TriggerHeaders interface{}
+	// GeneratedIDs are the identifiers FillMissingIDs added to the context (Set and Log).
+	GeneratedIDs map[string]interface{}
+	// Params is the activity's parameter object (Input, LogInput or ExceptionLogInput).
+	Params interface{}
+	// AdditionalLog holds the additionalLogParams key-value pairs.
+	AdditionalLog interface{}
+}
+
+// Record is a log record while it passes through the enrichers and processors of a RecordBuilder.
+type Record struct {
+	Entry
+	// Context is the calling activity; Input is the call's input.
+	Context activity.Context
+	Input   *RecordInput
+	// Params are the parameters extracted from Input.Params.
+	Params map[string]interface{}
+}
+
+// Enricher adds fields to a record.
+type Enricher interface {
+	Enrich(r *Record)
+}
+
+// EnricherFunc adapts a function to an Enricher.
+type EnricherFunc func(r *Record)
+
+// Enrich calls f(r).
+func (f EnricherFunc) Enrich(r *Record) {
+	f(r)
+}
+
+// Processor inspects or rewrites a record after enrichment. Returning false drops the record.
+type Processor interface {
+	Process(r *Record) bool
+}
+
+// ProcessorFunc adapts a function to a Processor.
+type ProcessorFunc func(r *Record) bool
+
+// Process calls f(r).
+func (f ProcessorFunc) Process(r *Record) bool {
+	return f(r)
+}
+
+type namedEnricher struct {
+	name string
+	Enricher
+}
+
+type namedProcessor struct {
+	name string
+	Processor
+}
+
+var (
+	stageMu    sync.RWMutex
+	enrichers  []namedEnricher
+	processors []namedProcessor
+)
+
+// RegisterEnricher adds an enricher run by every Custom Log activity after the built-in
+// fields are set. Enrichers run in registration order; registering an existing name
+// replaces it in place.
+func RegisterEnricher(name string, e Enricher) {
+	stageMu.Lock()
+	defer stageMu.Unlock()
+	next := make([]namedEnricher, 0, len(enrichers)+1)
+	replaced := false
+	for _, s := range enrichers {
+		if s.name == name {
+			s.Enricher, replaced = e, true
+		}
+		next = append(next, s)
+	}
+	if !replaced {
+		next = append(next, namedEnricher{name: name, Enricher: e})
+	}
+	enrichers = next
+}
+
+// RegisterProcessor adds a processor run by every Custom Log activity after all enrichers.
+// Processors run in registration order; registering an existing name replaces it in place.
+func RegisterProcessor(name string, p Processor) {
+	stageMu.Lock()
+	defer stageMu.Unlock()
+	next := make([]namedProcessor, 0, len(processors)+1)
+	replaced := false
+	for _, s := range processors {
+		if s.name == name {
+			s.Processor, replaced = p, true
+		}
+		next = append(next, s)
+	}
+	if !replaced {
+		next = append(next, namedProcessor{name: name, Processor: p})
+	}
+	processors = next
+}
+
+// registeredStages returns the current stages. The slices are replaced, never modified,
+// on registration, so callers may iterate them without holding the lock.
+func registeredStages() ([]namedEnricher, []namedProcessor) {
+	stageMu.RLock()
+	defer stageMu.RUnlock()
+	return enrichers, processors
+}
+
+// RecordConfig configures the RecordBuilder of one activity instance.
+type RecordConfig struct {
+	// LoggerPrefix prefixes the generated logger name: <prefix>.<app>.<flow>.<activity>.
+	LoggerPrefix string
+	// LogFormat and LoggerName are the activity defaults, used when the call does not set them.
+	LogFormat  string
+	LoggerName string
+	// FlowContext merges the customFlowInfo stored in flow scope by Set and Log.
+	FlowContext bool
+	// Enrichers and Processors run before the registered ones, for this activity only.
+	Enrichers  []Enricher
+	Processors []Processor
+	// Clock captures the record time (default: Now).
+	Clock Clock
+}
+
+// RecordBuilder builds log entries for an activity. The built-in enrichers set, in order:
+// base fields, Header and contextParams (or customFlowInfo), parameters, the flowInfo
+// suffix, additionalLogParams and traceID. Later enrichers overwrite fields set by earlier ones.
+type RecordBuilder struct {
+	cfg       RecordConfig
+	enrichers []Enricher
+}
+
+// NewRecordBuilder creates a RecordBuilder for cfg.
+func NewRecordBuilder(cfg RecordConfig) *RecordBuilder {
+	b := &RecordBuilder{cfg: cfg}
+	b.enrichers = append(b.enrichers, EnricherFunc(enrichBase))
+	if cfg.FlowContext {
+		b.enrichers = append(b.enrichers, EnricherFunc(enrichFlowContext))
+	} else {
+		b.enrichers = append(b.enrichers, EnricherFunc(enrichHeader))
+	}
+	b.enrichers = append(b.enrichers, EnricherFunc(enrichParams), EnricherFunc(enrichFlowInfo), EnricherFunc(enrichAdditionalLog), EnricherFunc(enrichTrace))
+	return b
+}
+
+// Build runs the enrichers and processors for one call and returns the entry to emit,
+// or nil if a processor dropped it.
+func (b *RecordBuilder) Build(ctx activity.Context, in *RecordInput) *Entry {
+	r := &Record{
+		Entry:   Entry{Time: b.now(), Level: strings.ToUpper(in.Level), Data: make(map[string]interface{})},
+		Context: ctx,
+		Input:   in,
+		Params:  ExtractParamsFromInput(in.Params),
+	}
+	if tc := ctx.GetTracingContext(); tc != nil {
+		r.TraceID = tc.TraceID()
+		r.SpanID = tc.SpanID()
+	}
+	for _, e := range b.enrichers {
+		e.Enrich(r)
+	}
+	r.Format = b.resolveFormat(r)
+	r.LoggerName = b.resolveLoggerName(r)
+
+	regEnrichers, regProcessors := registeredStages()
+	for _, e := range b.cfg.Enrichers {
+		e.Enrich(r)
+	}
+	for _, e := range regEnrichers {
+		e.Enrich(r)
+	}
+	for _, p := range b.cfg.Processors {
+		if !p.Process(r) {
+			return nil
+		}
+	}
+	for _, p := range regProcessors {
+		if !p.Process(r) {
+			return nil
+		}
+	}
+	return &r.Entry
+}
+
+func (b *RecordBuilder) now() time.Time {
+	if b.cfg.Clock != nil {
+		return b.cfg.Clock()
+	}
+	return Now()
+}
+
+// resolveFormat: logFormat parameter, else a logFormat field (e.g. from customFlowInfo), else the activity default.
+func (b *RecordBuilder) resolveFormat(r *Record) string {
+	if v := paramString(r.Params, "logFormat"); v != "" {
+		return v
+	}
+	if v := r.Data["logFormat"]; v != nil && fmt.Sprint(v) != "" {
+		return fmt.Sprint(v)
+	}
+	return b.cfg.LogFormat
+}
+
+// resolveLoggerName: loggerName parameter, else the activity default, else <prefix>.<app>.<flow>.<activity>.
+func (b *RecordBuilder) resolveLoggerName(r *Record) string {
+	if v := paramString(r.Params, "loggerName"); v != "" {
+		return v
+	}
+	if b.cfg.LoggerName != "" {
+		return b.cfg.LoggerName
+	}
+	return fmt.Sprintf("%s.%s.%s.%s", b.cfg.LoggerPrefix, engine.GetAppName(), r.Context.ActivityHost().Name(), r.Context.Name())
+}
+
+func paramString(params map[string]interface{}, key string) string {
+	if v, ok := params[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// LevelDisplay returns the level as written in the "level" field (INFO -> Info).
+func LevelDisplay(level string) string {
+	switch level {
+	case "INFO":
+		return "Info"
+	case "DEBUG":
+		return "Debug"
+	case "ERROR":
+		return "Error"
+	case "WARN":
+		return "Warn"
+	}
+	return level
+}
+
+// baseKeys are the record fields set by enrichBase (and the format selectors); values taken
+// from trigger headers never replace them.
+var baseKeys = map[string]bool{
+	"applicationName": true, "processName": true, "jobId": true, "processInstanceId": true,
+	"parentProcessInstanceId": true, "rootProcessInstanceId": true, "level": true,
+	"activityName": true, "timeStamp": true, "message": true, "logFormat": true, "loggerName": true,
+}
+
+func enrichBase(r *Record) {
+	host := r.Context.ActivityHost()
+	r.Data["applicationName"] = engine.GetAppName()
+	r.Data["processName"] = host.Name()
+	r.Data["jobId"] = host.ID()
+	r.Data["processInstanceId"] = host.ID()
+	r.Data["level"] = LevelDisplay(r.Level)
+	r.Data["activityName"] = r.Context.Name()
+	r.Data["timeStamp"] = defaultTimestampConfig().Format(r.Time)
+	r.Data["message"] = r.Input.Message
+	if parentID, rootID := FlowLineage(r.Context); parentID != "" {
+		r.Data["parentProcessInstanceId"] = parentID
+		r.Data["rootProcessInstanceId"] = rootID
+	}
+}
+
+// enrichHeader adds the generated IDs and the fields extracted from the trigger headers,
+// then the Header fields (sessionId, correlationId, ...) and contextParams. Without a
+// tracing context, the traceparent header supplies the record's trace and span IDs.
+func enrichHeader(r *Record) {
+	for k, v := range r.Input.GeneratedIDs {
+		r.Data[k] = v
+		r.setSection(k, SectionContext)
+	}
+	trigger := TriggerHeaderFields(r.Input.TriggerHeaders)
+	for k, v := range trigger {
+		if _, ok := r.Data[k]; ok {
+			continue
+		}
+		r.Data[k] = v
+		r.setSection(k, SectionContext)
+	}
+	for k, v := range ExtractHeaderFields(r.Input.Header) {
+		r.Data[k] = v
+		r.setSection(k, SectionContext)
+	}
+	for k, v := range ExtractKeyValuePairs(r.Input.ContextParams) {
+		r.Data[k] = v
+		r.setSection(k, SectionContext)
+	}
+	// Header or contextParams may replace the extracted traceID
+	if traceID, ok := trigger["traceID"].(string); ok && r.TraceID == "" && r.Data["traceID"] == traceID {
+		r.TraceID = traceID
+		r.SpanID, _ = trigger["spanID"].(string)
+	}
+}
+
+// enrichFlowContext adds the Header and contextParams stored in flow scope by Set and Log,
+// including those inherited from the root flow of a subflow (see CustomFlowInfo).
+func enrichFlowContext(r *Record) {
+	info := CustomFlowInfo(r.Context)
+	if len(info) == 0 {
+		warnNoContext(r.Context, fmt.Sprintf("no Set and Log Message or Update Log Context stored it for flow instance [%s]", r.Context.ActivityHost().ID()))
+	}
+	for k, v := range info {
+		if k != "message" && k != "loglevel" && v != nil && v != "" {
+			r.Data[k] = v
+			r.setSection(k, SectionContext)
+		}
+	}
+}
+
+// enrichParams adds the parameters (loggerName, logFormat, targetSystem, errorCode, ...).
+// A non-empty "message" parameter replaces the message; other parameters never touch it.
+func enrichParams(r *Record) {
+	for k, v := range r.Params {
+		if k == "message" {
+			if v != nil && fmt.Sprint(v) != "" {
+				r.Data["message"] = v
+			}
+			continue
+		}
+		r.Data[k] = v
+		r.setSection(k, SectionCustom)
+	}
+}
+
+// enrichFlowInfo appends the flow instance ID, flow name and activity name to the message.
+func enrichFlowInfo(r *Record) {
+	if r.Input.FlowInfo {
+		host := r.Context.ActivityHost()
+		r.Data["message"] = fmt.Sprintf("%v. FlowInstanceID [%s], Flow [%s], Activity [%s].", r.Data["message"], host.ID(), host.Name(), r.Context.Name())
+	}
+}
+
+func enrichAdditionalLog(r *Record) {
+	for k, v := range ExtractKeyValuePairs(r.Input.AdditionalLog) {
+		r.Data[k] = v
+		r.setSection(k, SectionCustom)
+	}
+}
+
+// enrichTrace adds traceID, the OpenTelemetry/OpenTracing trace ID (e.g. Jaeger).
+func enrichTrace(r *Record) {
+	if r.TraceID != "" {
+		r.Data["traceID"] = r.TraceID
+	}
+}
+
+// flowContextPrefix is the prefix of the flow context attributes (ParentFlowId, ...) the
+// flow engine sets on every flow instance.
+const flowContextPrefix = "_fctx."
+
+// flowScope returns the scope holding the flow variables of the calling flow: the master
+// scope of a flow instance, which an embedded subflow shares with the flow that started it.
+// A host that is not a flow instance gets its entry in the context store, keyed by its
+// instance ID. It is nil when the host has no instance ID.
+func flowScope(ctx activity.Context) data.Scope {
+	host := ctx.ActivityHost()
+	if host == nil {
+		warnNoContext(ctx, "the activity has no host")
+		return nil
+	}
+	inst, ok := host.Scope().(*instance.Instance)
+	if !ok {
+		if host.ID() == "" {
+			warnNoContext(ctx, "the activity host is not a flow instance and has no instance ID")
+			return nil
+		}
+		return storeScope{flowID: host.ID()}
+	}
+	restoreScopedContexts()
+	return inst.GetMasterScope()
+}
+
+// FlowLineage returns the instance IDs of the flow that started the calling subflow and of
+// the outermost flow, read from the flow context attributes the engine sets on every
+// instance. Both are empty for a main flow. For a subflow started as an independent
+// instance the root is not known and the parent is returned as root.
+func FlowLineage(ctx activity.Context) (parentID, rootID string) {
+	host := ctx.ActivityHost()
+	if host == nil || host.Scope() == nil {
+		return "", ""
+	}
+	v, _ := host.Scope().GetValue(flowContextPrefix + "ParentFlowId")
+	if parentID = toString(v); parentID == "" {
+		return "", ""
+	}
+	if inst, ok := host.Scope().(*instance.Instance); ok {
+		if v, _ := inst.GetMasterScope().GetValue(flowContextPrefix + "FlowId"); toString(v) != "" {
+			return parentID, toString(v)
+		}
+	}
+	return parentID, parentID
+}
+
+// GetFlowVariable reads a flow-scoped variable (key without the TIB_Flow: prefix) of the
+// calling flow. A subflow reads the value set by the flow that started it unless it has
+// set the variable itself.
+func GetFlowVariable(ctx activity.Context, key string) (interface{}, bool) {
+	return scopeValue(flowScope(ctx), key)
+}
+
+func scopeValue(scope data.Scope, key string) (interface{}, bool) {
+	if scope == nil {
+		return nil, false
+	}
+	val, exist := scope.GetValue("TIB_Flow:" + key)
+	if attr, ok := val.(*data.Attribute); ok {
+		if attr != nil {
+			return attr.Value(), exist
+		}
+		return nil, exist
+	}
+	return val, exist
+}
+
+// SetFlowVariable stores a flow-scoped variable (key without the TIB_Flow: prefix) in the
+// master scope of the calling flow, so it is visible to every activity of the flow, to its
+// subflows and, once set by a subflow, to the flow that started it.
+func SetFlowVariable(ctx activity.Context, key string, value interface{}) {
+	if scope := flowScope(ctx); scope != nil {
+		_ = scope.SetValue("TIB_Flow:"+key, value)
+	}
+}
+
+// CustomFlowInfo returns the customFlowInfo of the calling flow, shared by a main flow and
+// its embedded subflows. The result is a new map.
+func CustomFlowInfo(ctx activity.Context) map[string]interface{} {
+	v, _ := GetFlowVariable(ctx, CustomFlowInfoKey)
+	m, _ := v.(map[string]interface{})
+	return copyFlowInfo(m)
+}
+
+// StoreCustomFlowInfo stores info as the customFlowInfo of the calling flow and returns it.
+// In a subflow info is merged over the context set by the flows that started it, so keys the
+// subflow does not set keep their inherited values; a main flow replaces its context.
+func StoreCustomFlowInfo(ctx activity.Context, info map[string]interface{}) map[string]interface{} {
+	if parentID, _ := FlowLineage(ctx); parentID != "" {
+		merged := CustomFlowInfo(ctx)
+		for k, v := range info {
+			merged[k] = v
+		}
+		info = merged
+	}
+	SetFlowVariable(ctx, CustomFlowInfoKey, info)
+	return info
+}
@@ -0,0 +1,248 @@
+package logutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/data"
+	"github.com/project-flogo/core/support/log"
+	"github.com/project-flogo/core/support/test"
+	"github.com/project-flogo/flow/definition"
+	"github.com/project-flogo/flow/instance"
+	_ "github.com/project-flogo/flow/model/simple"
+	flowsupport "github.com/project-flogo/flow/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRecordContext returns a context of a non-flow host; its flow variables live in the
+// context store until ReleaseFlowContext(flowID).
+func newRecordContext(flowID string) activity.Context {
+	host := &test.TestActivityHost{HostId: flowID, HostData: data.NewSimpleScope(nil, nil)}
+	return test.NewActivityContextWithAction(activity.ToMetadata(), host)
+}
+
+func TestRecordBuilderBuild(t *testing.T) {
+	b := NewRecordBuilder(RecordConfig{LoggerPrefix: "flogo.test", LogFormat: "json"})
+	e := b.Build(newRecordContext("42"), &RecordInput{
+		Level:         "warn",
+		FlowInfo:      true,
+		Header:        map[string]interface{}{"correlationId": "c-1", "ignored": "x"},
+		ContextParams: map[string]interface{}{"keyValuePair": []interface{}{map[string]interface{}{"name": "orderId", "value": "o-9"}}},
+		Params:        map[string]interface{}{"message": "order delayed", "targetSystem": "SAP"},
+		AdditionalLog: map[string]interface{}{"region": "eu"},
+	})
+	require.NotNil(t, e)
+
+	assert.Equal(t, "WARN", e.Level)
+	assert.Equal(t, "json", e.Format)
+	assert.Equal(t, "flogo.test..."+"Test TaskOld", e.LoggerName)
+	assert.Equal(t, "Warn", e.Data["level"])
+	assert.Equal(t, "42", e.Data["jobId"])
+	assert.Equal(t, "order delayed. FlowInstanceID [42], Flow [], Activity [Test TaskOld].", e.Data["message"])
+	assert.Equal(t, "c-1", e.Data["correlationId"])
+	assert.NotContains(t, e.Data, "ignored")
+	assert.Equal(t, "o-9", e.Data["orderId"])
+	assert.Equal(t, "SAP", e.Data["targetSystem"])
+	assert.Equal(t, "eu", e.Data["region"])
+	assert.Equal(t, map[string]string{"orderId": SectionContext, "region": SectionCustom}, e.Sections)
+}
+
+func TestRecordBuilderErrorMessageKeepsMessage(t *testing.T) {
+	b := NewRecordBuilder(RecordConfig{FlowContext: true, LoggerName: "flogo.errors"})
+	e := b.Build(newRecordContext("1"), &RecordInput{
+		Level:   "ERROR",
+		Message: "payment failed",
+		Params:  map[string]interface{}{"errorCode": "PAY-7", "errorMessage": "card declined", "loggerName": "flogo.payments", "logFormat": "text"},
+	})
+	require.NotNil(t, e)
+
+	assert.Equal(t, "payment failed", e.Data["message"])
+	assert.Equal(t, "card declined", e.Data["errorMessage"])
+	assert.Equal(t, "PAY-7", e.Data["errorCode"])
+	assert.Equal(t, "flogo.payments", e.LoggerName)
+	assert.Equal(t, "text", e.Format)
+}
+
+func TestRecordBuilderStages(t *testing.T) {
+	var order []string
+	RegisterEnricher("record-test", EnricherFunc(func(r *Record) {
+		if r.Params["stageTest"] != nil {
+			order = append(order, "registered enricher")
+			r.Data["tenant"] = "acme"
+		}
+	}))
+	RegisterProcessor("record-test", ProcessorFunc(func(r *Record) bool {
+		if r.Params["stageTest"] == nil {
+			return true
+		}
+		order = append(order, "registered processor")
+		return r.Level != "DEBUG"
+	}))
+
+	b := NewRecordBuilder(RecordConfig{
+		Enrichers: []Enricher{EnricherFunc(func(r *Record) {
+			order = append(order, "activity enricher")
+			assert.Equal(t, "1", r.Data["jobId"], "built-in enrichers run first")
+		})},
+		Processors: []Processor{ProcessorFunc(func(r *Record) bool {
+			order = append(order, "activity processor")
+			delete(r.Data, "activityName")
+			return true
+		})},
+	})
+
+	e := b.Build(newRecordContext("1"), &RecordInput{Level: "INFO", Params: map[string]interface{}{"message": "m", "stageTest": true}})
+	require.NotNil(t, e)
+	assert.Equal(t, []string{"activity enricher", "registered enricher", "activity processor", "registered processor"}, order)
+	assert.Equal(t, "acme", e.Data["tenant"])
+	assert.NotContains(t, e.Data, "activityName")
+
+	assert.Nil(t, b.Build(newRecordContext("1"), &RecordInput{Level: "DEBUG", Params: map[string]interface{}{"stageTest": true}}))
+}
+
+func TestFlowContextInSubflow(t *testing.T) {
+	ctx := newRecordContext("42-1")
+	t.Cleanup(func() { ReleaseFlowContext("42-1") })
+	_ = ctx.ActivityHost().Scope().SetValue("_fctx.ParentFlowId", "42")
+	parentID, rootID := FlowLineage(ctx)
+	assert.Equal(t, "42", parentID)
+	assert.Equal(t, "42", rootID)
+	main, _ := FlowLineage(newRecordContext("42"))
+	assert.Empty(t, main)
+
+	SetFlowVariable(ctx, CustomFlowInfoKey, map[string]interface{}{"correlationId": "c-1", "orderId": "o-9", "message": "m"})
+	assert.Equal(t, map[string]interface{}{"correlationId": "c-1", "orderId": "o-9", "message": "m"}, CustomFlowInfo(ctx))
+
+	b := NewRecordBuilder(RecordConfig{FlowContext: true, LoggerName: "flogo.sub"})
+	e := b.Build(ctx, &RecordInput{Level: "INFO", Message: "in subflow"})
+	require.NotNil(t, e)
+	assert.Equal(t, "c-1", e.Data["correlationId"])
+	assert.Equal(t, "o-9", e.Data["orderId"])
+	assert.Equal(t, "in subflow", e.Data["message"])
+	assert.Equal(t, "42", e.Data["parentProcessInstanceId"])
+	assert.Equal(t, "42", e.Data["rootProcessInstanceId"])
+}
+
+// chainFlows is a main flow whose task "main" starts subflow A, whose task "a" starts subflow B
+// with task "b". Each task runs chainSteps[task name] in the real flow engine, and
+// chainSteps[task name+":after"] once its subflow completed.
+var chainFlows = []string{"main", "a", "b"}
+
+var chainSteps map[string]func(ctx activity.Context)
+
+type chainActivity struct{}
+
+func (chainActivity) Metadata() *activity.Metadata { return activity.ToMetadata() }
+
+func (chainActivity) Eval(ctx activity.Context) (bool, error) {
+	if step := chainSteps[ctx.Name()]; step != nil {
+		step(ctx)
+	}
+	for i, name := range chainFlows[:len(chainFlows)-1] {
+		if name == ctx.Name() {
+			return false, instance.StartSubFlow(ctx, "chain:"+chainFlows[i+1], nil)
+		}
+	}
+	return true, nil
+}
+
+func (chainActivity) PostEval(ctx activity.Context, userData interface{}) (bool, error) {
+	if step := chainSteps[ctx.Name()+":after"]; step != nil {
+		step(ctx)
+	}
+	return true, nil
+}
+
+type chainProvider struct{}
+
+func (chainProvider) GetFlow(uri string) (*definition.DefinitionRep, error) {
+	return chainDefinition(uri[len("chain:"):])
+}
+
+func chainDefinition(task string) (*definition.DefinitionRep, error) {
+	rep := &definition.DefinitionRep{}
+	err := json.Unmarshal([]byte(fmt.Sprintf(`{"name":"flow-%s","model":"flogo-simple","tasks":[{"id":"%s","name":"%s","activity":{"ref":"%s"}}]}`,
+		task, task, task, activity.GetRef(chainActivity{}))), rep)
+	return rep, err
+}
+
+func init() {
+	_ = activity.Register(chainActivity{})
+}
+
+// runFlowChain runs the main flow and its subflows A and B with steps.
+func runFlowChain(t *testing.T, steps map[string]func(ctx activity.Context)) {
+	t.Helper()
+	chainSteps = steps
+	t.Cleanup(func() { chainSteps = nil })
+	flowsupport.InitDefaultDefLookup(flowsupport.NewFlowManager(chainProvider{}), nil)
+	rep, err := chainDefinition("main")
+	require.NoError(t, err)
+	def, err := definition.NewDefinition(rep)
+	require.NoError(t, err)
+	inst, err := instance.NewIndependentInstance("chain-1", "", def, nil, log.RootLogger(), context.Background())
+	require.NoError(t, err)
+	require.True(t, inst.Start(nil))
+	for inst.DoStep() {
+	}
+}
+
+func TestFlowContextInNestedSubflows(t *testing.T) {
+	var infoA, infoB, infoMain map[string]interface{}
+	var parentA, rootA, parentB, rootB string
+	var orderB interface{}
+	runFlowChain(t, map[string]func(ctx activity.Context){
+		"main": func(ctx activity.Context) {
+			StoreCustomFlowInfo(ctx, map[string]interface{}{"sessionId": "s-main", "correlationId": "c-main"})
+			SetFlowVariable(ctx, "orderId", "o-main")
+		},
+		"a": func(ctx activity.Context) {
+			infoA = StoreCustomFlowInfo(ctx, map[string]interface{}{"correlationId": "c-a", "trackingId": "t-a"})
+			SetFlowVariable(ctx, "orderId", "o-a")
+			parentA, rootA = FlowLineage(ctx)
+		},
+		"b": func(ctx activity.Context) {
+			StoreCustomFlowInfo(ctx, map[string]interface{}{"trackingId": "t-b"})
+			infoB = CustomFlowInfo(ctx)
+			parentB, rootB = FlowLineage(ctx)
+			orderB, _ = GetFlowVariable(ctx, "orderId")
+		},
+		"main:after": func(ctx activity.Context) {
+			infoMain = CustomFlowInfo(ctx)
+		},
+	})
+
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-main", "correlationId": "c-a", "trackingId": "t-a"}, infoA)
+	// B inherits from A and main and overrides what it sets
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-main", "correlationId": "c-a", "trackingId": "t-b"}, infoB)
+	assert.Equal(t, "o-a", orderB)
+	// the context is shared: main sees what its subflows stored
+	assert.Equal(t, infoB, infoMain)
+
+	// lineage comes from the flow context attributes of the engine; fail loudly if it stops resolving
+	require.Equal(t, "chain-1", parentA, "parent of subflow A")
+	require.Equal(t, "chain-1", rootA, "root of subflow A")
+	require.Equal(t, "chain-1-1", parentB, "parent of subflow B")
+	require.Equal(t, "chain-1", rootB, "root of subflow B")
+}
+
+func TestFlowVariableInMasterScope(t *testing.T) {
+	var main, sub interface{}
+	runFlowChain(t, map[string]func(ctx activity.Context){
+		"main": func(ctx activity.Context) {
+			SetFlowVariable(ctx, CustomFlowInfoKey, map[string]interface{}{"sessionId": "s-main"})
+			main, _ = ctx.ActivityHost().Scope().(*instance.Instance).GetMasterScope().GetValue("TIB_Flow:" + CustomFlowInfoKey)
+		},
+		"b": func(ctx activity.Context) {
+			SetFlowVariable(ctx, "orderId", "o-b")
+			sub, _ = ctx.ActivityHost().Scope().(*instance.Instance).GetMasterScope().GetValue("TIB_Flow:orderId")
+		},
+	})
+	// flow variables stay on the master scope, where flows map TIB_Flow:* from
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-main"}, main)
+	assert.Equal(t, "o-b", sub)
+}
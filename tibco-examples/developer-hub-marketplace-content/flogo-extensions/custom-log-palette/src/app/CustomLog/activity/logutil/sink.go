@@ -0,0 +1,224 @@
+package logutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EnvKeySinks selects the default output sinks for all Custom Log activities
+// (comma-separated sink names, e.g. "stdout,file"). Activity settings may override it.
+const EnvKeySinks = "FLOGO_CUSTOMLOG_SINKS"
+
+// DefaultSinks is used when neither the activity nor the environment selects a sink.
+const DefaultSinks = "stdout"
+
+// Entry is a single log record handed to a Sink.
+// Line is the output of FormatCustomLog; the remaining fields let structured sinks
+// (syslog, OTLP, ...) rebuild the record without parsing the line.
+// When Line is empty it is rendered from Data with Format before the first sink write.
+type Entry struct {
+	Time       time.Time
+	Level      string
+	LoggerName string
+	Format     string
+	Data       map[string]interface{}
+	Line       string
+	TraceID    string
+	SpanID     string
+	// Sections records the section (SectionContext, SectionCustom) of data keys whose
+	// section is not implied by their name; see FLOGO_CUSTOMLOG_KEY_SECTIONS.
+	Sections map[string]string
+}
+
+// setSection records the section of a data key that is not a standard key.
+func (e *Entry) setSection(k, section string) {
+	if _, ok := keySections[k]; ok {
+		return
+	}
+	if e.Sections == nil {
+		e.Sections = make(map[string]string)
+	}
+	e.Sections[k] = section
+}
+
+// render formats Line if the caller left formatting to the writer.
+func (e *Entry) render() {
+	if e.Line != "" {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = Now()
+	}
+	e.Line = formatCustomLogAt(e.Data, e.Sections, e.Format, e.Level, e.LoggerName, e.Time)
+}
+
+// timeOr returns the captured entry time, or now() for entries built without one.
+func (e *Entry) timeOr(now func() time.Time) time.Time {
+	if e.Time.IsZero() {
+		return now()
+	}
+	return e.Time
+}
+
+// Sink is an output destination for formatted log entries.
+// Implementations must be safe for concurrent use by many flow instances.
+type Sink interface {
+	Write(e *Entry) error
+	Close() error
+}
+
+// SinkFactory creates a Sink. It is called at most once per registered name;
+// the resulting Sink is shared by all activities that select it.
+type SinkFactory func() (Sink, error)
+
+var (
+	sinkMu        sync.Mutex
+	sinkFactories = make(map[string]SinkFactory)
+	sinkInstances = make(map[string]Sink)
+)
+
+func init() {
+	RegisterSink("stdout", func() (Sink, error) { return NewWriterSink(os.Stdout), nil })
+	RegisterSink("stderr", func() (Sink, error) { return NewWriterSink(os.Stderr), nil })
+	RegisterSink("discard", func() (Sink, error) { return NewWriterSink(io.Discard), nil })
+}
+
+// RegisterSink registers a sink factory under name (case-insensitive).
+// Registering an existing name replaces the factory; an already opened instance is kept.
+func RegisterSink(name string, factory SinkFactory) {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	sinkFactories[normalizeSinkName(name)] = factory
+}
+
+// IsSinkRegistered reports whether a sink factory exists for name.
+func IsSinkRegistered(name string) bool {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	_, ok := sinkFactories[normalizeSinkName(name)]
+	return ok
+}
+
+// RegisteredSinks returns the names of all registered sinks, sorted.
+func RegisteredSinks() []string {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	names := make([]string, 0, len(sinkFactories))
+	for n := range sinkFactories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetSink returns the shared Sink registered under name, opening it on first use.
+func GetSink(name string) (Sink, error) {
+	key := normalizeSinkName(name)
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	if s, ok := sinkInstances[key]; ok {
+		return s, nil
+	}
+	factory, ok := sinkFactories[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown log sink [%s]", name)
+	}
+	s, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log sink [%s]: %w", name, err)
+	}
+	sinkInstances[key] = s
+	return s, nil
+}
+
+// OpenSinks resolves a comma-separated list of sink names.
+// An empty list falls back to FLOGO_CUSTOMLOG_SINKS and then to DefaultSinks.
+func OpenSinks(names string) ([]Sink, error) {
+	var sinks []Sink
+	for _, n := range ParseSinkNames(names) {
+		s, err := GetSink(n)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+// ParseSinkNames splits a comma-separated sink list, applying the environment and built-in defaults.
+func ParseSinkNames(names string) []string {
+	if strings.TrimSpace(names) == "" {
+		names = os.Getenv(EnvKeySinks)
+	}
+	if strings.TrimSpace(names) == "" {
+		names = DefaultSinks
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, n := range strings.Split(names, ",") {
+		n = normalizeSinkName(n)
+		if n != "" && !seen[n] {
+			out = append(out, n)
+			seen[n] = true
+		}
+	}
+	return out
+}
+
+// CloseSinks closes all opened sinks. Sinks are reopened on the next GetSink.
+func CloseSinks() error {
+	sinkMu.Lock()
+	defer sinkMu.Unlock()
+	var firstErr error
+	for name, s := range sinkInstances {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(sinkInstances, name)
+	}
+	return firstErr
+}
+
+// WriteEntry writes e to every sink. All sinks are attempted; the first error is returned.
+func WriteEntry(sinks []Sink, e *Entry) error {
+	e.render()
+	var firstErr error
+	for _, s := range sinks {
+		if err := s.Write(e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func normalizeSinkName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// WriterSink writes each entry's Line, newline terminated, to an io.Writer.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a Sink writing lines to w. Writes are serialized so lines never interleave.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Write(e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.WriteString(s.w, e.Line+"\n")
+	return err
+}
+
+// Close is a no-op: the underlying writer (stdout, stderr) is owned by the process.
+func (s *WriterSink) Close() error {
+	return nil
+}
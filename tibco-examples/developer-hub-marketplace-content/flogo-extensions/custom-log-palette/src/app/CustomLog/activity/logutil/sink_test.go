@@ -0,0 +1,41 @@
+package logutil
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSinkNames(t *testing.T) {
+	t.Setenv(EnvKeySinks, "")
+	assert.Equal(t, []string{"stdout"}, ParseSinkNames(""))
+	assert.Equal(t, []string{"stdout", "stderr"}, ParseSinkNames(" STDOUT, stderr ,stdout,"))
+
+	t.Setenv(EnvKeySinks, "discard")
+	assert.Equal(t, []string{"discard"}, ParseSinkNames(""))
+}
+
+func TestSinkRegistry(t *testing.T) {
+	var buf bytes.Buffer
+	RegisterSink("test-buffer", func() (Sink, error) { return NewWriterSink(&buf), nil })
+	defer CloseSinks()
+
+	assert.True(t, IsSinkRegistered("Test-Buffer"))
+	assert.Contains(t, RegisteredSinks(), "discard")
+
+	sinks, err := OpenSinks("test-buffer,discard")
+	assert.NoError(t, err)
+	assert.Len(t, sinks, 2)
+
+	again, err := GetSink("test-buffer")
+	assert.NoError(t, err)
+	assert.Same(t, sinks[0], again)
+
+	assert.NoError(t, WriteEntry(sinks, &Entry{Line: "first"}))
+	assert.NoError(t, WriteEntry(sinks, &Entry{Line: "second"}))
+	assert.Equal(t, "first\nsecond\n", buf.String())
+
+	_, err = OpenSinks("stdout,nope")
+	assert.Error(t, err)
+}
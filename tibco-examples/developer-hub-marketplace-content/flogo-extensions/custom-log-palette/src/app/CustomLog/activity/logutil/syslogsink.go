@@ -0,0 +1,371 @@
+package logutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Environment variables configuring the "syslog" sink.
+const (
+	EnvKeySyslogAddress      = "FLOGO_CUSTOMLOG_SYSLOG_ADDRESS"
+	EnvKeySyslogNetwork      = "FLOGO_CUSTOMLOG_SYSLOG_NETWORK"
+	EnvKeySyslogFormat       = "FLOGO_CUSTOMLOG_SYSLOG_FORMAT"
+	EnvKeySyslogFacility     = "FLOGO_CUSTOMLOG_SYSLOG_FACILITY"
+	EnvKeySyslogHostname     = "FLOGO_CUSTOMLOG_SYSLOG_HOSTNAME"
+	EnvKeySyslogSDID         = "FLOGO_CUSTOMLOG_SYSLOG_SD_ID"
+	EnvKeySyslogTLSCA        = "FLOGO_CUSTOMLOG_SYSLOG_TLS_CA"
+	EnvKeySyslogTLSInsecure  = "FLOGO_CUSTOMLOG_SYSLOG_TLS_INSECURE"
+	EnvKeySyslogWriteTimeout = "FLOGO_CUSTOMLOG_SYSLOG_WRITE_TIMEOUT"
+)
+
+// DefaultSyslogWriteTimeout bounds a tcp or tls write to a collector that stopped reading.
+const DefaultSyslogWriteTimeout = 5 * time.Second
+
+// Syslog message formats.
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164"
+)
+
+// DefaultSyslogSDID is the SD-ID of the structured-data element carrying the log data.
+// 32473 is the private enterprise number reserved for documentation (RFC 5612).
+const DefaultSyslogSDID = "customlog@32473"
+
+const syslogNilValue = "-"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+func init() {
+	RegisterSink("syslog", func() (Sink, error) {
+		cfg, err := SyslogSinkConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewSyslogSink(cfg)
+	})
+}
+
+// SyslogSinkConfig configures a SyslogSink.
+type SyslogSinkConfig struct {
+	// Network is "udp", "tcp" or "tls".
+	Network string
+	// Address is the collector host:port.
+	Address string
+	// Format is SyslogRFC5424 or SyslogRFC3164.
+	Format string
+	// Facility is the numeric syslog facility (0-23).
+	Facility int
+	// Hostname overrides os.Hostname() in the message header.
+	Hostname string
+	// SDID is the SD-ID of the structured-data element (RFC 5424 only).
+	SDID string
+	// TLSConfig is used when Network is "tls".
+	TLSConfig *tls.Config
+	// DialTimeout bounds each (re)connect attempt.
+	DialTimeout time.Duration
+	// WriteTimeout bounds each tcp or tls write; a timeout fails the write like a broken connection.
+	WriteTimeout time.Duration
+}
+
+// SyslogSinkConfigFromEnv reads the syslog sink configuration from FLOGO_CUSTOMLOG_SYSLOG_* variables.
+func SyslogSinkConfigFromEnv() (SyslogSinkConfig, error) {
+	cfg := SyslogSinkConfig{
+		Network:  strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeySyslogNetwork))),
+		Address:  os.Getenv(EnvKeySyslogAddress),
+		Format:   strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeySyslogFormat))),
+		Facility: syslogFacilities["local0"],
+		Hostname: os.Getenv(EnvKeySyslogHostname),
+		SDID:     os.Getenv(EnvKeySyslogSDID),
+	}
+	if cfg.Address == "" {
+		return cfg, fmt.Errorf("%s is required for the syslog sink", EnvKeySyslogAddress)
+	}
+	if v := os.Getenv(EnvKeySyslogWriteTimeout); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeySyslogWriteTimeout, v)
+		}
+		cfg.WriteTimeout = d
+	}
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeySyslogFacility))); v != "" {
+		if f, ok := syslogFacilities[v]; ok {
+			cfg.Facility = f
+		} else if n, err := strconv.Atoi(v); err == nil {
+			cfg.Facility = n
+		} else {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeySyslogFacility, v)
+		}
+	}
+	if cfg.Network == "tls" {
+		cfg.TLSConfig = &tls.Config{}
+		if v := os.Getenv(EnvKeySyslogTLSCA); v != "" {
+			pem, err := os.ReadFile(v)
+			if err != nil {
+				return cfg, fmt.Errorf("failed to read %s: %w", EnvKeySyslogTLSCA, err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return cfg, fmt.Errorf("no certificates found in %s [%s]", EnvKeySyslogTLSCA, v)
+			}
+			cfg.TLSConfig.RootCAs = pool
+		}
+		if v := os.Getenv(EnvKeySyslogTLSInsecure); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return cfg, fmt.Errorf("invalid %s [%s]", EnvKeySyslogTLSInsecure, v)
+			}
+			cfg.TLSConfig.InsecureSkipVerify = b
+		}
+	}
+	return cfg, nil
+}
+
+// SyslogSink sends entries to a syslog collector as RFC 5424 or RFC 3164 messages.
+// TCP and TLS use octet-counted framing (RFC 6587 / RFC 5425); a broken connection
+// is re-established on the next write.
+type SyslogSink struct {
+	cfg      SyslogSinkConfig
+	hostname string
+	procID   string
+	now      func() time.Time
+
+	mu     sync.Mutex
+	conn   net.Conn
+	failed atomic.Int64
+}
+
+// NewSyslogSink validates cfg and returns a sink. The connection is opened on first write.
+func NewSyslogSink(cfg SyslogSinkConfig) (*SyslogSink, error) {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.Format == "" {
+		cfg.Format = SyslogRFC5424
+	}
+	if cfg.SDID == "" {
+		cfg.SDID = DefaultSyslogSDID
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = DefaultSyslogWriteTimeout
+	}
+	switch cfg.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("invalid syslog network [%s], valid values=[udp, tcp, tls]", cfg.Network)
+	}
+	switch cfg.Format {
+	case SyslogRFC5424, SyslogRFC3164:
+	default:
+		return nil, fmt.Errorf("invalid syslog format [%s], valid values=[rfc5424, rfc3164]", cfg.Format)
+	}
+	if cfg.Facility < 0 || cfg.Facility > 23 {
+		return nil, fmt.Errorf("invalid syslog facility [%d]", cfg.Facility)
+	}
+	if cfg.Address == "" {
+		return nil, errors.New("syslog address is empty")
+	}
+	hostname := cfg.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	return &SyslogSink{
+		cfg:      cfg,
+		hostname: hostname,
+		procID:   strconv.Itoa(os.Getpid()),
+		now:      time.Now,
+	}, nil
+}
+
+func (s *SyslogSink) Write(e *Entry) error {
+	msg := s.format(e)
+	if s.cfg.Network != "udp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// One retry on a fresh connection covers collector restarts and idle disconnects.
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if err = s.dial(); err != nil {
+				continue
+			}
+		}
+		if s.cfg.Network != "udp" {
+			if err = s.conn.SetWriteDeadline(time.Now().Add(s.cfg.WriteTimeout)); err != nil {
+				s.conn.Close()
+				s.conn = nil
+				continue
+			}
+		}
+		if _, err = s.conn.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		// a timed-out write may have sent part of the frame, so the connection is dropped too
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.failed.Add(1)
+	return err
+}
+
+// Failed returns the number of entries that could not be sent (connection, write or timeout errors).
+func (s *SyslogSink) Failed() int64 {
+	return s.failed.Load()
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogSink) dial() error {
+	var conn net.Conn
+	var err error
+	if s.cfg.Network == "tls" {
+		dialer := &net.Dialer{Timeout: s.cfg.DialTimeout}
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.cfg.Address, s.cfg.TLSConfig)
+	} else {
+		conn, err = net.DialTimeout(s.cfg.Network, s.cfg.Address, s.cfg.DialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *SyslogSink) format(e *Entry) string {
+	pri := s.cfg.Facility*8 + SyslogSeverity(e.Level)
+	ts := e.timeOr(s.now)
+	appName := syslogHeaderField(toString(e.Data["applicationName"]), 48)
+	if s.cfg.Format == SyslogRFC3164 {
+		// <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+		tag := appName
+		if tag == syslogNilValue {
+			tag = "flogo"
+		}
+		return fmt.Sprintf("<%d>%s %s %s[%s]: %s", pri, ts.Format(time.Stamp),
+			syslogHeaderField(s.hostname, 255), tag, s.procID, e.Line)
+	}
+	// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+	var b strings.Builder
+	b.Grow(256 + len(e.Line))
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ", pri,
+		ts.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(s.hostname, 255), appName, s.procID,
+		syslogHeaderField(toString(e.Data["processName"]), 32))
+	b.WriteString(syslogStructuredData(s.cfg.SDID, e.Data))
+	if msg := toString(e.Data["message"]); msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(msg)
+	}
+	return b.String()
+}
+
+// SyslogSeverity maps a Custom Log level to a syslog severity.
+func SyslogSeverity(level string) int {
+	switch strings.ToUpper(level) {
+	case "ERROR":
+		return 3
+	case "WARN":
+		return 4
+	case "INFO":
+		return 6
+	case "DEBUG":
+		return 7
+	}
+	return 5
+}
+
+// syslogHeaderField restricts a header field to printable US-ASCII without spaces (RFC 5424 PRINTUSASCII).
+func syslogHeaderField(s string, max int) string {
+	if s == "" {
+		return syslogNilValue
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+// syslogStructuredData renders logData as a single SD-ELEMENT; message is carried in MSG.
+func syslogStructuredData(sdID string, logData map[string]interface{}) string {
+	var b strings.Builder
+	for _, k := range OrderedKeys(logData) {
+		if k == "message" {
+			continue
+		}
+		v := toString(logData[k])
+		if v == "" {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(syslogParamName(k))
+		b.WriteString(`="`)
+		b.WriteString(syslogParamValue(v))
+		b.WriteByte('"')
+	}
+	if b.Len() == 0 {
+		return syslogNilValue
+	}
+	return "[" + sdID + b.String() + "]"
+}
+
+// syslogParamName restricts an SD-NAME to 32 printable US-ASCII characters other than '=', ' ', ']' and '"'.
+func syslogParamName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > 32 {
+		b = b[:32]
+	}
+	return string(b)
+}
+
+// syslogParamValue escapes '"', '\' and ']' in a PARAM-VALUE.
+func syslogParamValue(s string) string {
+	if !strings.ContainsAny(s, `"\]`) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if r == '"' || r == '\\' || r == ']' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
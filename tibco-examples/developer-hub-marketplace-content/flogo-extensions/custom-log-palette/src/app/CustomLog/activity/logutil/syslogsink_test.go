@@ -0,0 +1,187 @@
+package logutil
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var syslogTestEntry = &Entry{
+	Level:      "WARN",
+	LoggerName: "flogo.test",
+	Data: map[string]interface{}{
+		"applicationName": "Order App",
+		"processName":     "ProcessOrder",
+		"correlationId":   `c-1"]\`,
+		"message":         "Payment delayed",
+	},
+	Line: "text line",
+}
+
+func fixedTime() time.Time {
+	return time.Date(2026, 3, 1, 10, 15, 30, 123456000, time.UTC)
+}
+
+func TestSyslogFormatRFC5424(t *testing.T) {
+	s, err := NewSyslogSink(SyslogSinkConfig{Address: "127.0.0.1:514", Facility: 16, Hostname: "host1"})
+	require.NoError(t, err)
+	s.now = fixedTime
+	s.procID = "42"
+
+	assert.Equal(t,
+		`<132>1 2026-03-01T10:15:30.123456Z host1 Order_App 42 ProcessOrder `+
+			`[customlog@32473 applicationName="Order App" processName="ProcessOrder" correlationId="c-1\"\]\\"] Payment delayed`,
+		s.format(syslogTestEntry))
+}
+
+func TestSyslogFormatRFC3164(t *testing.T) {
+	s, err := NewSyslogSink(SyslogSinkConfig{Address: "127.0.0.1:514", Format: SyslogRFC3164, Facility: 1, Hostname: "host1"})
+	require.NoError(t, err)
+	s.now = fixedTime
+	s.procID = "42"
+
+	assert.Equal(t, "<12>Mar  1 10:15:30 host1 Order_App[42]: text line", s.format(syslogTestEntry))
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	assert.Equal(t, 3, SyslogSeverity("ERROR"))
+	assert.Equal(t, 4, SyslogSeverity("warn"))
+	assert.Equal(t, 6, SyslogSeverity("INFO"))
+	assert.Equal(t, 7, SyslogSeverity("DEBUG"))
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	s, err := NewSyslogSink(SyslogSinkConfig{Network: "udp", Address: pc.LocalAddr().String(), Facility: 16})
+	require.NoError(t, err)
+	defer s.Close()
+	require.NoError(t, s.Write(syslogTestEntry))
+
+	buf := make([]byte, 2048)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "<132>1 "))
+	assert.True(t, strings.HasSuffix(string(buf[:n]), "] Payment delayed"))
+}
+
+// readOctetCounted reads one RFC 6587 octet-counted frame.
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	lenStr, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+func TestSyslogSinkTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			msg, err := readOctetCounted(r)
+			if err == nil {
+				received <- msg
+			}
+			// Drop the connection after one frame to force a reconnect.
+			conn.Close()
+		}
+	}()
+
+	s, err := NewSyslogSink(SyslogSinkConfig{Network: "tcp", Address: ln.Addr().String(), Facility: 16})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Write(syslogTestEntry))
+	first := <-received
+	assert.True(t, strings.HasSuffix(first, "Payment delayed"))
+
+	// Writes into a connection the peer already closed may be lost until the
+	// reset is observed; keep writing until the sink reconnects and delivers.
+	deadline := time.After(5 * time.Second)
+	for {
+		_ = s.Write(syslogTestEntry)
+		select {
+		case msg := <-received:
+			assert.True(t, strings.HasPrefix(msg, "<132>1 "))
+			return
+		case <-deadline:
+			t.Fatal("sink did not reconnect")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestSyslogSinkWriteTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	// the collector accepts connections but never reads
+	var conns []net.Conn
+	var mu sync.Mutex
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+
+	s, err := NewSyslogSink(SyslogSinkConfig{Network: "tcp", Address: ln.Addr().String(), WriteTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	defer s.Close()
+
+	start := time.Now()
+	err = s.Write(&Entry{Level: "INFO", Data: map[string]interface{}{"message": strings.Repeat("x", 32<<20)}})
+	require.Error(t, err)
+	var ne net.Error
+	assert.True(t, errors.As(err, &ne) && ne.Timeout(), err.Error())
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, int64(1), s.Failed())
+
+	t.Setenv(EnvKeySyslogAddress, "127.0.0.1:514")
+	t.Setenv(EnvKeySyslogWriteTimeout, "2s")
+	cfg, err := SyslogSinkConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, cfg.WriteTimeout)
+	t.Setenv(EnvKeySyslogWriteTimeout, "0")
+	_, err = SyslogSinkConfigFromEnv()
+	assert.Error(t, err)
+}
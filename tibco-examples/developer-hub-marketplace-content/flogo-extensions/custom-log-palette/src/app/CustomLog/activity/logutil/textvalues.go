@@ -0,0 +1,225 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Environment variables configuring complex values in the "text" format.
+const (
+	EnvKeyTextFlatten  = "FLOGO_CUSTOMLOG_TEXT_FLATTEN"
+	EnvKeyTextMaxDepth = "FLOGO_CUSTOMLOG_TEXT_MAX_DEPTH"
+	EnvKeyTextRedact   = "FLOGO_CUSTOMLOG_TEXT_REDACT"
+)
+
+// DefaultTextMaxDepth limits flattening and JSON nesting of complex values in the text format.
+const DefaultTextMaxDepth = 5
+
+// RedactedValue replaces the value of a redacted key.
+const RedactedValue = "[REDACTED]"
+
+// TextConfig configures how maps, slices and structs are written by the "text" format
+// (and %kvp of the "pattern" format). By default they are written as compact JSON.
+type TextConfig struct {
+	// Flatten writes one a_key.sub="value" pair per leaf instead of a JSON value;
+	// slice elements use their index (a_tags.0="x").
+	Flatten bool
+	// MaxDepth is the deepest level flattened or written as JSON (0 = DefaultTextMaxDepth).
+	// Deeper values are written as compact JSON (flatten) or a JSON-encoded string.
+	MaxDepth int
+	// RedactKeys are key names (any level, case-insensitive) or dotted paths
+	// (e.g. errorData.card.number) whose values are replaced by RedactedValue.
+	RedactKeys []string
+}
+
+// TextConfigFromEnv reads the configuration from FLOGO_CUSTOMLOG_TEXT_* variables.
+func TextConfigFromEnv() (TextConfig, error) {
+	cfg := TextConfig{RedactKeys: splitList(os.Getenv(EnvKeyTextRedact))}
+	if v := strings.TrimSpace(os.Getenv(EnvKeyTextFlatten)); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyTextFlatten, v)
+		}
+		cfg.Flatten = b
+	}
+	if v := strings.TrimSpace(os.Getenv(EnvKeyTextMaxDepth)); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid %s [%s]", EnvKeyTextMaxDepth, v)
+		}
+		cfg.MaxDepth = n
+	}
+	return cfg, nil
+}
+
+var (
+	textConfigOnce sync.Once
+	textConfig     TextConfig
+)
+
+// defaultTextConfig is read once; an invalid environment (reported by ActivityConfig.Validate)
+// keeps compact JSON without redaction.
+func defaultTextConfig() TextConfig {
+	textConfigOnce.Do(func() {
+		if cfg, err := TextConfigFromEnv(); err == nil {
+			textConfig = cfg
+		}
+	})
+	return textConfig
+}
+
+func (c TextConfig) maxDepth() int {
+	if c.MaxDepth > 0 {
+		return c.MaxDepth
+	}
+	return DefaultTextMaxDepth
+}
+
+// redacted reports whether the key at path (dotted, without the a_ prefix) is redacted.
+func (c TextConfig) redacted(path, key string) bool {
+	for _, k := range c.RedactKeys {
+		if strings.EqualFold(k, key) || strings.EqualFold(k, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// textPairs writes the non-empty log data as key="value" pairs separated by ", ",
+// with the keys named by names (a_key by default).
+func textPairs(logData map[string]interface{}, cfg TextConfig, names keyNames) string {
+	var pairs []string
+	add := func(key, value string) {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, value))
+	}
+	for _, k := range names.order(OrderedKeys(logData)) {
+		v := logData[k]
+		name := names.name(k)
+		if cfg.redacted(k, k) {
+			if toString(v) != "" {
+				add(name, RedactedValue)
+			}
+			continue
+		}
+		if cfg.Flatten && isComplexValue(v) {
+			cfg.flatten(k, name, v, 1, add)
+			continue
+		}
+		if isComplexValue(v) {
+			var b strings.Builder
+			writeJSONTyped(&b, cfg.redact(k, v), cfg.maxDepth())
+			add(name, b.String())
+			continue
+		}
+		if s := toString(v); s != "" {
+			add(name, s)
+		}
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// flatten calls add for every leaf below path, written as out plus the child keys.
+// Containers below MaxDepth are written as compact JSON.
+func (c TextConfig) flatten(path, out string, v interface{}, depth int, add func(key, value string)) {
+	if !isComplexValue(v) {
+		add(out, toString(v))
+		return
+	}
+	if depth > c.maxDepth() {
+		var b strings.Builder
+		writeJSONTyped(&b, c.redact(path, v), c.maxDepth())
+		add(out, b.String())
+		return
+	}
+	forEachChild(v, func(key string, child interface{}) {
+		childPath := path + "." + key
+		if c.redacted(childPath, key) {
+			add(out+"."+key, RedactedValue)
+			return
+		}
+		c.flatten(childPath, out+"."+key, child, depth+1, add)
+	})
+}
+
+// redact returns v with redacted keys below path replaced by RedactedValue.
+// Without RedactKeys, v is returned unchanged.
+func (c TextConfig) redact(path string, v interface{}) interface{} {
+	if len(c.RedactKeys) == 0 || !isComplexValue(v) {
+		return v
+	}
+	if rv := reflect.Indirect(reflect.ValueOf(v)); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		out := make([]interface{}, 0, rv.Len())
+		forEachChild(v, func(key string, child interface{}) {
+			out = append(out, c.redact(path+"."+key, child))
+		})
+		return out
+	}
+	out := make(map[string]interface{})
+	forEachChild(v, func(key string, child interface{}) {
+		if c.redacted(path+"."+key, key) {
+			out[key] = RedactedValue
+		} else {
+			out[key] = c.redact(path+"."+key, child)
+		}
+	})
+	return out
+}
+
+// isComplexValue reports maps, slices, arrays and structs (other than []byte).
+func isComplexValue(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.([]byte); ok {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// forEachChild calls fn for the elements of a slice (by index) or the entries of a map
+// (by sorted key). Structs are visited through their JSON encoding.
+func forEachChild(v interface{}, fn func(key string, child interface{})) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			fn(strconv.Itoa(i), rv.Index(i).Interface())
+		}
+		return
+	case reflect.Map:
+		keys := make([]string, 0, rv.Len())
+		values := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			keys = append(keys, k)
+			values[k] = iter.Value().Interface()
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fn(k, values[k])
+		}
+		return
+	}
+	var b strings.Builder
+	writeJSONTyped(&b, v, DefaultJSONMaxDepth)
+	if generic, ok := decodeJSONValue(b.String()); ok && isComplexValue(generic) {
+		forEachChild(generic, fn)
+	}
+}
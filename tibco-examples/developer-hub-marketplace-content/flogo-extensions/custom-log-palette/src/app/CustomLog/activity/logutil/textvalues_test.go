@@ -0,0 +1,72 @@
+package logutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func complexTextData() map[string]interface{} {
+	return map[string]interface{}{
+		"message": "failed",
+		"errorData": map[string]interface{}{
+			"code":  "X",
+			"card":  map[string]interface{}{"number": "4111", "brand": "visa"},
+			"lines": []interface{}{1, "two"},
+		},
+		"headers": map[string]string{"Authorization": "Bearer t", "Accept": "json"},
+	}
+}
+
+func TestTextPairsComplexValuesAsJSON(t *testing.T) {
+	got := textPairs(complexTextData(), TextConfig{}, prefixKeys)
+	assert.Equal(t, `a_message="failed", `+
+		`a_errorData="{\"card\":{\"brand\":\"visa\",\"number\":\"4111\"},\"code\":\"X\",\"lines\":[1,\"two\"]}", `+
+		`a_headers="{\"Accept\":\"json\",\"Authorization\":\"Bearer t\"}"`, got)
+
+	// other formats no longer print Go's map[k:v] form either
+	assert.Equal(t, `{"a":1,"b":[true]}`, toString(map[string]interface{}{"b": []bool{true}, "a": 1}))
+}
+
+func TestTextPairsFlatten(t *testing.T) {
+	got := textPairs(complexTextData(), TextConfig{Flatten: true}, prefixKeys)
+	assert.Equal(t, `a_message="failed", `+
+		`a_errorData.card.brand="visa", a_errorData.card.number="4111", a_errorData.code="X", `+
+		`a_errorData.lines.0="1", a_errorData.lines.1="two", `+
+		`a_headers.Accept="json", a_headers.Authorization="Bearer t"`, got)
+
+	got = textPairs(complexTextData(), TextConfig{Flatten: true, MaxDepth: 1}, prefixKeys)
+	assert.Contains(t, got, `a_errorData.card="{\"brand\":\"visa\",\"number\":\"4111\"}"`)
+	assert.Contains(t, got, `a_errorData.code="X"`)
+}
+
+func TestTextPairsRedact(t *testing.T) {
+	cfg := TextConfig{RedactKeys: []string{"authorization", "errorData.card.number", "message"}}
+	for _, flatten := range []bool{false, true} {
+		cfg.Flatten = flatten
+		got := textPairs(complexTextData(), cfg, prefixKeys)
+		assert.NotContains(t, got, "4111")
+		assert.NotContains(t, got, "Bearer")
+		assert.Contains(t, got, `a_message="[REDACTED]"`)
+		assert.Contains(t, got, "visa")
+	}
+	// the record itself is not modified
+	data := complexTextData()
+	textPairs(data, cfg, prefixKeys)
+	assert.Equal(t, "4111", data["errorData"].(map[string]interface{})["card"].(map[string]interface{})["number"])
+}
+
+func TestTextConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyTextFlatten, "true")
+	t.Setenv(EnvKeyTextMaxDepth, "2")
+	t.Setenv(EnvKeyTextRedact, "password, card.number")
+	cfg, err := TextConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, TextConfig{Flatten: true, MaxDepth: 2, RedactKeys: []string{"password", "card.number"}}, cfg)
+
+	t.Setenv(EnvKeyTextFlatten, "sometimes")
+	_, err = TextConfigFromEnv()
+	assert.Error(t, err)
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidLogFormat)
+}
@@ -0,0 +1,203 @@
+package logutil
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Environment variables configuring record timestamps.
+const (
+	EnvKeyTimeZone      = "FLOGO_CUSTOMLOG_TIME_ZONE"
+	EnvKeyTimeFormat    = "FLOGO_CUSTOMLOG_TIME_FORMAT"
+	EnvKeyTimePrecision = "FLOGO_CUSTOMLOG_TIME_PRECISION"
+)
+
+// Timestamp styles for TimestampConfig.Style.
+const (
+	// TimeRFC3339 writes RFC 3339 with the zone offset, e.g. 2026-02-13T15:53:47.498Z.
+	TimeRFC3339 = "rfc3339"
+	// TimeLegacy writes the layouts used before timestamps were configurable
+	// (2026-02-13T16:53:47.498000, text format 2026-02-13T16:53:47,498) without an offset.
+	TimeLegacy = "legacy"
+	// TimeEpochMillis and TimeEpochNanos write the Unix time as an integer.
+	TimeEpochMillis = "epoch_millis"
+	TimeEpochNanos  = "epoch_nanos"
+)
+
+// Fractional second precisions for TimeRFC3339.
+const (
+	PrecisionSeconds = "s"
+	PrecisionMillis  = "ms"
+	PrecisionMicros  = "us"
+	PrecisionNanos   = "ns"
+)
+
+const legacyTimeLayout = "2006-01-02T15:04:05.000000"
+
+// TimestampConfig controls how record timestamps (the "timeStamp" field and the
+// timestamp of the text, json, logfmt and pattern formats) are written.
+type TimestampConfig struct {
+	// Location is the time zone (default UTC).
+	Location *time.Location
+	// Style is TimeRFC3339 (default), TimeLegacy, TimeEpochMillis or TimeEpochNanos.
+	Style string
+	// Precision is the fraction of TimeRFC3339: PrecisionSeconds, PrecisionMillis (default),
+	// PrecisionMicros or PrecisionNanos.
+	Precision string
+}
+
+// NewTimestampConfig validates the style and precision and fills in defaults.
+func NewTimestampConfig(loc *time.Location, style, precision string) (TimestampConfig, error) {
+	cfg := TimestampConfig{Location: loc, Style: style, Precision: precision}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	if cfg.Style == "" {
+		cfg.Style = TimeRFC3339
+	}
+	if cfg.Precision == "" {
+		cfg.Precision = PrecisionMillis
+	}
+	switch cfg.Style {
+	case TimeRFC3339, TimeLegacy, TimeEpochMillis, TimeEpochNanos:
+	default:
+		return cfg, fmt.Errorf("invalid time format [%s], valid values=[rfc3339, legacy, epoch_millis, epoch_nanos]", cfg.Style)
+	}
+	switch cfg.Precision {
+	case PrecisionSeconds, PrecisionMillis, PrecisionMicros, PrecisionNanos:
+	default:
+		return cfg, fmt.Errorf("invalid time precision [%s], valid values=[s, ms, us, ns]", cfg.Precision)
+	}
+	return cfg, nil
+}
+
+// TimestampConfigFromEnv reads FLOGO_CUSTOMLOG_TIME_ZONE ("UTC", "Local" or an IANA name),
+// FLOGO_CUSTOMLOG_TIME_FORMAT and FLOGO_CUSTOMLOG_TIME_PRECISION.
+func TimestampConfigFromEnv() (TimestampConfig, error) {
+	loc := time.UTC
+	if zone := strings.TrimSpace(os.Getenv(EnvKeyTimeZone)); zone != "" {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return TimestampConfig{}, fmt.Errorf("invalid %s [%s]", EnvKeyTimeZone, zone)
+		}
+	}
+	return NewTimestampConfig(loc,
+		strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeyTimeFormat))),
+		strings.ToLower(strings.TrimSpace(os.Getenv(EnvKeyTimePrecision))))
+}
+
+// Format writes t in the configured zone and format.
+func (c TimestampConfig) Format(t time.Time) string {
+	switch c.Style {
+	case TimeEpochMillis:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case TimeEpochNanos:
+		return strconv.FormatInt(t.UnixNano(), 10)
+	case TimeLegacy:
+		return t.In(c.location()).Format(legacyTimeLayout)
+	}
+	return t.In(c.location()).Format(rfc3339Layout(c.Precision))
+}
+
+func rfc3339Layout(precision string) string {
+	switch precision {
+	case PrecisionSeconds:
+		return "2006-01-02T15:04:05Z07:00"
+	case PrecisionMicros:
+		return "2006-01-02T15:04:05.000000Z07:00"
+	case PrecisionNanos:
+		return "2006-01-02T15:04:05.000000000Z07:00"
+	}
+	return "2006-01-02T15:04:05.000Z07:00"
+}
+
+func (c TimestampConfig) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// formatText is Format, except that the legacy text format separates milliseconds with a comma.
+func (c TimestampConfig) formatText(t time.Time) string {
+	if c.Style == TimeLegacy {
+		t = t.In(c.location())
+		return t.Format("2006-01-02T15:04:05") + "," + fmt.Sprintf("%03d", t.Nanosecond()/1000000)
+	}
+	return c.Format(t)
+}
+
+// Parse reads a timestamp written by any TimestampConfig. Legacy timestamps, which have
+// no offset, are read in c.Location.
+func (c TimestampConfig) Parse(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation(legacyTimeLayout, s, c.location()); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05,000", s, c.location()); err == nil {
+		return t, true
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// 16 or more digits cannot be milliseconds before the year 2286.
+		if len(s) >= 16 {
+			return time.Unix(0, n), true
+		}
+		return time.UnixMilli(n), true
+	}
+	return time.Time{}, false
+}
+
+var timestampConfig atomic.Pointer[TimestampConfig]
+
+// defaultTimestampConfig is the configuration set with SetTimestampConfig, else read from
+// the environment on first use. An invalid environment (reported by ActivityConfig.Validate)
+// falls back to the defaults.
+func defaultTimestampConfig() TimestampConfig {
+	if cfg := timestampConfig.Load(); cfg != nil {
+		return *cfg
+	}
+	cfg, err := TimestampConfigFromEnv()
+	if err != nil {
+		cfg, _ = NewTimestampConfig(nil, "", "")
+	}
+	timestampConfig.CompareAndSwap(nil, &cfg)
+	return *timestampConfig.Load()
+}
+
+// SetTimestampConfig replaces the timestamp configuration of all activities.
+func SetTimestampConfig(cfg TimestampConfig) {
+	timestampConfig.Store(&cfg)
+}
+
+// Clock supplies the instant captured for each record.
+type Clock func() time.Time
+
+var clock atomic.Pointer[Clock]
+
+// SetClock replaces the clock used for new records (nil restores time.Now), so that
+// formatted output is deterministic in tests.
+func SetClock(c Clock) {
+	if c == nil {
+		clock.Store(nil)
+		return
+	}
+	clock.Store(&c)
+}
+
+// Now returns the current time of the clock set with SetClock, else time.Now().
+func Now() time.Time {
+	if c := clock.Load(); c != nil {
+		return (*c)()
+	}
+	return time.Now()
+}
@@ -0,0 +1,98 @@
+package logutil
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withTimestampConfig installs cfg for the duration of the test.
+func withTimestampConfig(t *testing.T, cfg TimestampConfig) {
+	prev := defaultTimestampConfig()
+	SetTimestampConfig(cfg)
+	t.Cleanup(func() { SetTimestampConfig(prev) })
+}
+
+func TestTimestampConfigFormat(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	now := fixedTime()
+
+	tests := []struct {
+		loc       *time.Location
+		style     string
+		precision string
+		want      string
+	}{
+		{nil, "", "", "2026-03-01T10:15:30.123Z"},
+		{nil, TimeRFC3339, PrecisionSeconds, "2026-03-01T10:15:30Z"},
+		{nil, TimeRFC3339, PrecisionMicros, "2026-03-01T10:15:30.123456Z"},
+		{berlin, TimeRFC3339, PrecisionNanos, "2026-03-01T11:15:30.123456000+01:00"},
+		{berlin, TimeLegacy, "", "2026-03-01T11:15:30.123456"},
+		{nil, TimeEpochMillis, "", "1772360130123"},
+		{nil, TimeEpochNanos, "", "1772360130123456000"},
+	}
+	for _, tt := range tests {
+		cfg, err := NewTimestampConfig(tt.loc, tt.style, tt.precision)
+		require.NoError(t, err)
+		got := cfg.Format(now)
+		assert.Equal(t, tt.want, got)
+
+		parsed, ok := cfg.Parse(got)
+		require.True(t, ok, got)
+		want := now.Truncate(time.Millisecond)
+		if tt.precision == PrecisionSeconds {
+			want = now.Truncate(time.Second)
+		} else if tt.precision != "" || tt.style == TimeLegacy || tt.style == TimeEpochNanos {
+			want = now
+		}
+		assert.True(t, want.Equal(parsed), "%s parsed as %v", got, parsed)
+	}
+
+	legacy, _ := NewTimestampConfig(berlin, TimeLegacy, "")
+	assert.Equal(t, "2026-03-01T11:15:30,123", legacy.formatText(now))
+
+	_, err = NewTimestampConfig(nil, "iso", "")
+	assert.Error(t, err)
+	_, err = NewTimestampConfig(nil, "", "cs")
+	assert.Error(t, err)
+}
+
+func TestTimestampConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyTimeZone, "Local")
+	t.Setenv(EnvKeyTimeFormat, "EPOCH_MILLIS")
+	cfg, err := TimestampConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, cfg.Location)
+	assert.Equal(t, TimeEpochMillis, cfg.Style)
+
+	t.Setenv(EnvKeyTimeZone, "Mars/Olympus")
+	_, err = TimestampConfigFromEnv()
+	assert.Error(t, err)
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidTimestamp)
+}
+
+func TestRecordTimestampsShareOneInstant(t *testing.T) {
+	cfg, _ := NewTimestampConfig(nil, TimeRFC3339, PrecisionMicros)
+	withTimestampConfig(t, cfg)
+	SetClock(fixedTime)
+	t.Cleanup(func() { SetClock(nil) })
+
+	for _, format := range []string{FormatText, FormatJSON, FormatLogfmt} {
+		e := NewRecordBuilder(RecordConfig{LoggerPrefix: "flogo.test", LogFormat: format}).
+			Build(newRecordContext("1"), &RecordInput{Level: "INFO", Message: "hi"})
+		require.NotNil(t, e)
+		assert.Equal(t, fixedTime(), e.Time)
+		assert.Equal(t, "2026-03-01T10:15:30.123456Z", e.Data["timeStamp"])
+		e.render()
+		assert.Equal(t, 2, strings.Count(e.Line, "2026-03-01T10:15:30.123456Z"), e.Line)
+	}
+
+	other := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := NewRecordBuilder(RecordConfig{Clock: func() time.Time { return other }}).
+		Build(newRecordContext("1"), &RecordInput{Level: "INFO"})
+	assert.Equal(t, other, e.Time)
+}
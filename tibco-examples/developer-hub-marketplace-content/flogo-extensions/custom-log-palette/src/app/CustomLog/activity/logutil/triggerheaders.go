@@ -0,0 +1,331 @@
+package logutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EnvKeyHeaderRules overrides the header names read for a field, as comma-separated
+// field=Header-A|Header-B rules (e.g. "correlationId=X-Correlation-ID|X-Request-ID,sessionId=X-Session-ID").
+// An empty list ("baggage=") disables a field.
+const EnvKeyHeaderRules = "FLOGO_CUSTOMLOG_HEADER_RULES"
+
+// Fields of HeaderRules with W3C Trace Context parsing; every other field copies the header value.
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+	HeaderBaggage     = "baggage"
+)
+
+// BaggagePrefix namespaces the baggage members in the context params (baggage.tenant), so
+// callers cannot replace record fields or Header values through the baggage header.
+const BaggagePrefix = "baggage."
+
+// W3C Baggage limits: members beyond them are dropped.
+const (
+	maxBaggageMembers = 180
+	maxBaggageBytes   = 8192
+)
+
+// HeaderRules maps a field to the trigger header names it is read from, in order of
+// preference (case-insensitive; the first non-empty header wins).
+type HeaderRules map[string][]string
+
+// DefaultHeaderRules returns the rules used without FLOGO_CUSTOMLOG_HEADER_RULES.
+func DefaultHeaderRules() HeaderRules {
+	return HeaderRules{
+		HeaderTraceparent: {"traceparent"},
+		HeaderTracestate:  {"tracestate"},
+		HeaderBaggage:     {"baggage"},
+		"correlationId":   {"X-Correlation-ID", "X-Request-ID"},
+	}
+}
+
+// HeaderRulesFromEnv returns DefaultHeaderRules with the FLOGO_CUSTOMLOG_HEADER_RULES overrides.
+func HeaderRulesFromEnv() (HeaderRules, error) {
+	rules := DefaultHeaderRules()
+	for _, rule := range splitList(os.Getenv(EnvKeyHeaderRules)) {
+		field, names, ok := strings.Cut(rule, "=")
+		field = strings.TrimSpace(field)
+		if !ok || field == "" {
+			return rules, fmt.Errorf("invalid %s entry [%s], expected field=Header-A|Header-B", EnvKeyHeaderRules, rule)
+		}
+		if baseKeys[field] {
+			return rules, fmt.Errorf("invalid %s entry [%s]: %s is a record field", EnvKeyHeaderRules, rule, field)
+		}
+		var headers []string
+		for _, name := range strings.Split(names, "|") {
+			if name = strings.TrimSpace(name); name != "" {
+				headers = append(headers, name)
+			}
+		}
+		rules[field] = headers
+	}
+	return rules, nil
+}
+
+var (
+	headerRulesOnce sync.Once
+	headerRules     HeaderRules
+)
+
+// defaultHeaderRules is read once; an invalid environment (reported by ActivityConfig.Validate)
+// keeps DefaultHeaderRules.
+func defaultHeaderRules() HeaderRules {
+	headerRulesOnce.Do(func() {
+		rules, err := HeaderRulesFromEnv()
+		if err != nil {
+			rules = DefaultHeaderRules()
+		}
+		headerRules = rules
+	})
+	return headerRules
+}
+
+// Traceparent is a parsed W3C traceparent header.
+type Traceparent struct {
+	Version  string
+	TraceID  string
+	ParentID string
+	Flags    string
+}
+
+// Sampled reports the sampled trace flag.
+func (t Traceparent) Sampled() bool {
+	return len(t.Flags) == 2 && hexValue(t.Flags[1])&1 == 1
+}
+
+// ParseTraceparent parses a W3C Trace Context traceparent header
+// (version "-" trace-id "-" parent-id "-" trace-flags, lowercase hex). Version ff, all-zero
+// IDs and, for version 00, trailing data are invalid. Later versions may append fields.
+func ParseTraceparent(s string) (Traceparent, error) {
+	s = strings.Trim(s, " \t")
+	// 2 + 1 + 32 + 1 + 16 + 1 + 2
+	const size = 55
+	if len(s) < size || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return Traceparent{}, fmt.Errorf("invalid traceparent [%s]", s)
+	}
+	t := Traceparent{Version: s[:2], TraceID: s[3:35], ParentID: s[36:52], Flags: s[53:55]}
+	switch {
+	case !isLowerHex(t.Version) || t.Version == "ff":
+		return Traceparent{}, fmt.Errorf("invalid traceparent version [%s]", t.Version)
+	case t.Version == "00" && len(s) != size:
+		return Traceparent{}, fmt.Errorf("invalid traceparent [%s]: unexpected data after trace-flags", s)
+	case len(s) > size && s[size] != '-':
+		return Traceparent{}, fmt.Errorf("invalid traceparent [%s]", s)
+	case !isLowerHex(t.TraceID) || strings.Trim(t.TraceID, "0") == "":
+		return Traceparent{}, fmt.Errorf("invalid traceparent trace-id [%s]", t.TraceID)
+	case !isLowerHex(t.ParentID) || strings.Trim(t.ParentID, "0") == "":
+		return Traceparent{}, fmt.Errorf("invalid traceparent parent-id [%s]", t.ParentID)
+	case !isLowerHex(t.Flags):
+		return Traceparent{}, fmt.Errorf("invalid traceparent trace-flags [%s]", t.Flags)
+	}
+	return t, nil
+}
+
+// ParseBaggage parses a W3C Baggage header (comma-separated key=value members with
+// optional ;properties). Values are percent-decoded; properties are ignored. Invalid
+// members, and members beyond 180 entries or 8192 bytes, are dropped.
+func ParseBaggage(s string) map[string]string {
+	out := make(map[string]string)
+	size := 0
+	for _, member := range strings.Split(s, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+		if len(out) == maxBaggageMembers || size+len(member) > maxBaggageBytes {
+			break
+		}
+		size += len(member) + 1
+		kv, _, _ := strings.Cut(member, ";")
+		k, v, ok := strings.Cut(kv, "=")
+		k, v = strings.Trim(k, " \t"), strings.Trim(v, " \t")
+		if !ok || !isToken(k) || !isBaggageValue(v) {
+			continue
+		}
+		decoded, err := url.PathUnescape(v)
+		if err != nil {
+			continue
+		}
+		out[k] = decoded
+	}
+	return out
+}
+
+// validTracestate reports whether a tracestate header has at most 32 key=value members.
+func validTracestate(s string) bool {
+	members := 0
+	for _, member := range strings.Split(s, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(member, "=")
+		if !ok || k == "" || v == "" || len(v) > 256 || strings.ContainsAny(k, " \t") {
+			return false
+		}
+		members++
+	}
+	return members > 0 && members <= 32
+}
+
+// ExtractTriggerHeaders reads correlation fields from the raw headers of a trigger
+// (map[string]string, map[string][]string, map[string]interface{} or a JSON object) with rules.
+// header receives the plain fields (correlationId, ...) and, from a valid traceparent,
+// traceID, spanID and traceFlags, plus traceState. contextParams receives the baggage members
+// as BaggagePrefix+key. Rules for record fields (jobId, level, ...) are ignored.
+func ExtractTriggerHeaders(headers interface{}, rules HeaderRules) (header, contextParams map[string]interface{}) {
+	header = make(map[string]interface{})
+	contextParams = make(map[string]interface{})
+	values := headerValues(headers)
+	if len(values) == 0 {
+		return header, contextParams
+	}
+	lookup := func(field string) string {
+		for _, name := range rules[field] {
+			if v := strings.TrimSpace(values[strings.ToLower(name)]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	fields := make([]string, 0, len(rules))
+	for field := range rules {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		switch {
+		case field == HeaderTraceparent, field == HeaderTracestate, baseKeys[field]:
+			continue
+		case field == HeaderBaggage:
+			for k, v := range ParseBaggage(lookup(field)) {
+				contextParams[BaggagePrefix+k] = v
+			}
+		default:
+			if v := lookup(field); v != "" {
+				header[field] = v
+			}
+		}
+	}
+	// tracestate is only meaningful with a valid traceparent
+	if tp, err := ParseTraceparent(lookup(HeaderTraceparent)); err == nil {
+		header["traceID"] = tp.TraceID
+		header["spanID"] = tp.ParentID
+		header["traceFlags"] = tp.Flags
+		if ts := lookup(HeaderTracestate); ts != "" && validTracestate(ts) {
+			header["traceState"] = ts
+		}
+	}
+	return header, contextParams
+}
+
+// TriggerHeaderFields extracts the trigger headers with the configured rules into one map.
+func TriggerHeaderFields(headers interface{}) map[string]interface{} {
+	header, out := ExtractTriggerHeaders(headers, defaultHeaderRules())
+	for k, v := range header {
+		out[k] = v
+	}
+	return out
+}
+
+// headerValues returns the headers with lowercase names. Repeated headers are joined
+// with "," as HTTP list headers are.
+func headerValues(headers interface{}) map[string]string {
+	out := make(map[string]string)
+	add := func(name string, v interface{}) {
+		var s string
+		switch x := v.(type) {
+		case nil:
+			return
+		case []string:
+			s = strings.Join(x, ",")
+		case []interface{}:
+			parts := make([]string, 0, len(x))
+			for _, p := range x {
+				parts = append(parts, headerString(p))
+			}
+			s = strings.Join(parts, ",")
+		default:
+			s = headerString(x)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if prev, ok := out[name]; ok && prev != "" {
+			s = prev + "," + s
+		}
+		out[name] = s
+	}
+	switch h := headers.(type) {
+	case map[string]string:
+		for k, v := range h {
+			add(k, v)
+		}
+	case map[string][]string:
+		for k, v := range h {
+			add(k, v)
+		}
+	case map[string]interface{}:
+		for k, v := range h {
+			add(k, v)
+		}
+	case string:
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(h), &m); err == nil {
+			return headerValues(m)
+		}
+	}
+	return out
+}
+
+// headerString converts a header value; Kafka headers arrive as []byte.
+func headerString(v interface{}) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return toString(v)
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func hexValue(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
+
+// isToken reports an RFC 7230 token, the syntax of baggage keys.
+func isToken(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isBaggageValue reports the baggage-octet syntax: printable US-ASCII without
+// space, '"', ',', ';' and '\'.
+func isBaggageValue(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || c == '"' || c == ',' || c == ';' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package logutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tp, err := ParseTraceparent(testTraceparent)
+	require.NoError(t, err)
+	assert.Equal(t, Traceparent{Version: "00", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7", Flags: "01"}, tp)
+	assert.True(t, tp.Sampled())
+
+	// later versions may append fields after a dash
+	tp, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	require.NoError(t, err)
+	assert.False(t, tp.Sampled())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x",
+		testTraceparent + "-extra",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01x",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestParseBaggage(t *testing.T) {
+	b := ParseBaggage(" userId=alice , serverNode = DF%2028 ;prop=1, isProduction=false,bad key=x,empty=,noValue")
+	assert.Equal(t, map[string]string{"userId": "alice", "serverNode": "DF 28", "isProduction": "false", "empty": ""}, b)
+
+	// a '+' is not a space in baggage values; invalid percent-encoding drops the member
+	b = ParseBaggage("a=1+1,b=%zz,c=%E2%9C%93")
+	assert.Equal(t, map[string]string{"a": "1+1", "c": "✓"}, b)
+
+	members := make([]string, 200)
+	for i := range members {
+		members[i] = "k" + strings.Repeat("x", i%3) + string(rune('a'+i%26)) + strings.Repeat("0", i/26) + "=v"
+	}
+	assert.Len(t, ParseBaggage(strings.Join(members, ",")), maxBaggageMembers)
+	assert.Len(t, ParseBaggage("a="+strings.Repeat("x", maxBaggageBytes)), 0)
+}
+
+func TestExtractTriggerHeaders(t *testing.T) {
+	header, params := ExtractTriggerHeaders(map[string]interface{}{
+		"Traceparent":  testTraceparent,
+		"TraceState":   "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7",
+		"Baggage":      []interface{}{"userId=alice", "tenant=acme"},
+		"X-Request-ID": []byte("req-7"),
+	}, DefaultHeaderRules())
+	assert.Equal(t, map[string]interface{}{
+		"correlationId": "req-7",
+		"traceID":       "4bf92f3577b34da6a3ce929d0e0e4736",
+		"spanID":        "00f067aa0ba902b7",
+		"traceFlags":    "01",
+		"traceState":    "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7",
+	}, header)
+	assert.Equal(t, map[string]interface{}{"baggage.userId": "alice", "baggage.tenant": "acme"}, params)
+
+	// X-Correlation-ID is preferred; tracestate is discarded with an invalid traceparent
+	header, _ = ExtractTriggerHeaders(map[string][]string{
+		"X-Correlation-Id": {"corr-1"},
+		"X-Request-Id":     {"req-7"},
+		"Traceparent":      {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		"Tracestate":       {"congo=t61rcWkgMzE"},
+	}, DefaultHeaderRules())
+	assert.Equal(t, map[string]interface{}{"correlationId": "corr-1"}, header)
+
+	header, params = ExtractTriggerHeaders(`{"x-session":"s-1","x-job":"j-1","baggage":"a=1"}`, HeaderRules{"sessionId": {"X-Session"}, "jobId": {"X-Job"}})
+	assert.Equal(t, map[string]interface{}{"sessionId": "s-1"}, header, "rules for record fields are ignored")
+	assert.Empty(t, params)
+}
+
+func TestHeaderRulesFromEnv(t *testing.T) {
+	t.Setenv(EnvKeyHeaderRules, "correlationId=X-Trace-Token|X-Request-ID, sessionId=X-Session-ID, baggage=")
+	rules, err := HeaderRulesFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"X-Trace-Token", "X-Request-ID"}, rules["correlationId"])
+	assert.Equal(t, []string{"X-Session-ID"}, rules["sessionId"])
+	assert.Empty(t, rules[HeaderBaggage])
+	assert.Equal(t, []string{"traceparent"}, rules[HeaderTraceparent])
+
+	t.Setenv(EnvKeyHeaderRules, "X-Session-ID")
+	_, err = HeaderRulesFromEnv()
+	assert.Error(t, err)
+	t.Setenv(EnvKeyHeaderRules, "jobId=X-Job-ID")
+	_, err = HeaderRulesFromEnv()
+	assert.Error(t, err)
+	cfg := &ActivityConfig{}
+	assertErrorCode(t, cfg.Validate(), ErrCodeInvalidContext)
+}
+
+func TestRecordBuilderTriggerHeaders(t *testing.T) {
+	b := NewRecordBuilder(RecordConfig{LoggerPrefix: "flogo.test", LogFormat: "json"})
+	e := b.Build(newRecordContext("42"), &RecordInput{
+		Level: "info",
+		TriggerHeaders: map[string]string{
+			"traceparent":      testTraceparent,
+			"baggage":          "tenant=acme,orderId=o-1,jobId=x,level=z,message=forged,correlationId=b-1",
+			"X-Correlation-ID": "corr-1",
+		},
+		Header:        map[string]interface{}{"sessionId": "s-1"},
+		ContextParams: map[string]interface{}{"keyValuePair": []interface{}{map[string]interface{}{"name": "orderId", "value": "o-9"}}},
+	})
+	require.NotNil(t, e)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", e.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", e.SpanID)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", e.Data["traceID"])
+	assert.Equal(t, "corr-1", e.Data["correlationId"])
+	assert.Equal(t, "s-1", e.Data["sessionId"])
+	assert.Equal(t, "acme", e.Data["baggage.tenant"])
+	assert.Equal(t, "o-1", e.Data["baggage.orderId"])
+	assert.Equal(t, "o-9", e.Data["orderId"])
+	assert.Equal(t, SectionContext, e.Sections["baggage.tenant"])
+	// baggage cannot replace record fields or Header values
+	assert.Equal(t, "42", e.Data["jobId"])
+	assert.Equal(t, "Info", e.Data["level"])
+	assert.Empty(t, e.Data["message"])
+	assert.Equal(t, "x", e.Data["baggage.jobId"])
+
+	// an explicit Header traceID is not used as the record's trace ID
+	e = b.Build(newRecordContext("43"), &RecordInput{
+		Level:          "info",
+		TriggerHeaders: map[string]string{"traceparent": testTraceparent},
+		Header:         map[string]interface{}{"traceID": "manual"},
+	})
+	assert.Empty(t, e.TraceID)
+	assert.Equal(t, "manual", e.Data["traceID"])
+}
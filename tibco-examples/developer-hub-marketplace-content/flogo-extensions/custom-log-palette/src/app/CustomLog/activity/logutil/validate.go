@@ -0,0 +1,175 @@
+package logutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/project-flogo/core/activity"
+)
+
+// Error codes reported by the Custom Log activities.
+const (
+	ErrCodeInvalidLogLevel   = "LOGMESSAGE-001"
+	ErrCodeInvalidLogFormat  = "LOGMESSAGE-002"
+	ErrCodeInvalidLoggerName = "LOGMESSAGE-003"
+	ErrCodeInvalidSink       = "LOGMESSAGE-004"
+	ErrCodeInvalidEnvFields  = "LOGMESSAGE-005"
+	ErrCodeInvalidPattern    = "LOGMESSAGE-006"
+	ErrCodeInvalidTimestamp  = "LOGMESSAGE-007"
+	ErrCodeInvalidContext    = "LOGMESSAGE-008"
+	ErrCodeInvalidIDStrategy = "LOGMESSAGE-009"
+	ErrCodeInvalidAsync      = "LOGMESSAGE-010"
+)
+
+// loggerNamePattern accepts dot-separated segments such as flogo.CustomLog.orders-api.
+var loggerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_$-]+(\.[A-Za-z0-9_$-]+)*$`)
+
+// ActivityConfig is the static configuration of a Custom Log activity, validated once in New.
+type ActivityConfig struct {
+	Sinks          string
+	LevelThreshold string
+	LogFormat      string
+	LoggerName     string
+	// IDStrategy generates missing sessionId, correlationId and trackingId (Set and Log).
+	IDStrategy string
+}
+
+// Validate checks every configured value and returns an activity ConfigError with the
+// matching LOGMESSAGE code for the first invalid one.
+func (c *ActivityConfig) Validate() error {
+	if strings.TrimSpace(c.LevelThreshold) != "" {
+		if err := ValidateLogLevel(c.LevelThreshold); err != nil {
+			return err
+		}
+	}
+	if _, err := ThresholdFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidLogLevel, activity.ConfigError, nil)
+	}
+	if err := ValidateLogFormat(c.LogFormat); err != nil {
+		return err
+	}
+	if err := ValidateLoggerName(c.LoggerName); err != nil {
+		return err
+	}
+	if err := ValidateSinkNames(c.Sinks); err != nil {
+		return err
+	}
+	if err := ValidateIDStrategy(c.IDStrategy); err != nil {
+		return err
+	}
+	if _, err := EnvFieldsFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidEnvFields, activity.ConfigError, nil)
+	}
+	if err := ValidatePattern(PatternFromEnv()); err != nil {
+		return err
+	}
+	if _, err := JSONConfigFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidLogFormat, activity.ConfigError, nil)
+	}
+	if _, err := TextConfigFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidLogFormat, activity.ConfigError, nil)
+	}
+	if _, err := KeyLayoutFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidLogFormat, activity.ConfigError, nil)
+	}
+	if _, err := CEFConfigFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidLogFormat, activity.ConfigError, nil)
+	}
+	if _, err := LEEFConfigFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidLogFormat, activity.ConfigError, nil)
+	}
+	if _, err := ContextTTLFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidContext, activity.ConfigError, nil)
+	}
+	if _, err := HeaderRulesFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidContext, activity.ConfigError, nil)
+	}
+	if _, err := TimestampConfigFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidTimestamp, activity.ConfigError, nil)
+	}
+	if _, _, err := AsyncConfigFromEnv(); err != nil {
+		return activity.NewActivityError(err.Error(), ErrCodeInvalidAsync, activity.ConfigError, nil)
+	}
+	return nil
+}
+
+// ValidateLogLevel checks a Log Level value (INFO, DEBUG, ERROR, WARN; case-insensitive).
+func ValidateLogLevel(level string) error {
+	if LevelRank(level) < 0 {
+		return activity.NewActivityError(fmt.Sprintf("Invalid Log level [%s] configured. Valid values=[INFO, DEBUG, ERROR, WARN].",
+			strings.ToUpper(level)), ErrCodeInvalidLogLevel, activity.ConfigError, nil)
+	}
+	return nil
+}
+
+// ValidateLogFormat checks a log format name; empty selects the text format.
+func ValidateLogFormat(format string) error {
+	if strings.TrimSpace(format) == "" || IsLogFormat(format) {
+		return nil
+	}
+	return activity.NewActivityError(fmt.Sprintf("Invalid log format [%s] configured. Valid values=[%s].",
+		format, strings.Join(LogFormats(), ", ")), ErrCodeInvalidLogFormat, activity.ConfigError, nil)
+}
+
+// ValidatePattern checks that a pattern layout compiles.
+func ValidatePattern(pattern string) error {
+	if _, err := cachedPattern(pattern); err != nil {
+		return activity.NewActivityError(fmt.Sprintf("Invalid log pattern [%s] configured: %v.",
+			pattern, err), ErrCodeInvalidPattern, activity.ConfigError, nil)
+	}
+	return nil
+}
+
+// ValidateContextMode checks an Update Log Context mode; empty selects merge.
+func ValidateContextMode(mode string) error {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", ContextMerge, ContextReplace, ContextDelete:
+		return nil
+	}
+	return activity.NewActivityError(fmt.Sprintf("Invalid context mode [%s] configured. Valid values=[%s, %s, %s].",
+		mode, ContextMerge, ContextReplace, ContextDelete), ErrCodeInvalidContext, activity.ConfigError, nil)
+}
+
+// ValidateIDStrategy checks an ID strategy name (case-insensitive); empty selects none.
+func ValidateIDStrategy(strategy string) error {
+	if strings.TrimSpace(strategy) == "" {
+		return nil
+	}
+	for _, s := range idStrategies {
+		if strings.EqualFold(strings.TrimSpace(strategy), s) {
+			return nil
+		}
+	}
+	return activity.NewActivityError(fmt.Sprintf("Invalid ID strategy [%s] configured. Valid values=[%s].",
+		strategy, strings.Join(idStrategies, ", ")), ErrCodeInvalidIDStrategy, activity.ConfigError, nil)
+}
+
+// ValidateLoggerName checks a configured logger name; empty selects the generated default.
+func ValidateLoggerName(name string) error {
+	if name == "" || loggerNamePattern.MatchString(name) {
+		return nil
+	}
+	return activity.NewActivityError(fmt.Sprintf("Invalid logger name [%s] configured. Expected dot-separated segments of letters, digits, '_', '-' or '$'.",
+		name), ErrCodeInvalidLoggerName, activity.ConfigError, nil)
+}
+
+// ValidateSinkNames checks that every sink in the list (after defaults) is registered.
+func ValidateSinkNames(names string) error {
+	for _, n := range ParseSinkNames(names) {
+		if !IsSinkRegistered(n) {
+			return activity.NewActivityError(fmt.Sprintf("Unknown log sink [%s] configured. Valid values=[%s].",
+				n, strings.Join(RegisteredSinks(), ", ")), ErrCodeInvalidSink, activity.ConfigError, nil)
+		}
+	}
+	return nil
+}
+
+// OpenSinks opens the configured sinks, reporting failures as LOGMESSAGE-004.
+func (c *ActivityConfig) OpenSinks() ([]Sink, error) {
+	sinks, err := OpenSinks(c.Sinks)
+	if err != nil {
+		return nil, activity.NewActivityError(err.Error(), ErrCodeInvalidSink, activity.ConfigError, nil)
+	}
+	return sinks, nil
+}
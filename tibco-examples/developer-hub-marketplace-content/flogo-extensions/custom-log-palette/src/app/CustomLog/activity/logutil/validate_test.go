@@ -0,0 +1,45 @@
+package logutil
+
+import (
+	"testing"
+
+	"github.com/project-flogo/core/activity"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertErrorCode(t *testing.T, err error, code string) {
+	t.Helper()
+	actErr, ok := err.(*activity.Error)
+	if assert.True(t, ok, "expected *activity.Error, got %v", err) {
+		assert.Equal(t, code, actErr.Code())
+	}
+}
+
+func TestActivityConfigValidate(t *testing.T) {
+	t.Setenv(EnvKeySinks, "")
+	valid := &ActivityConfig{Sinks: "stdout, discard", LevelThreshold: "warn", LogFormat: "JSON", LoggerName: "flogo.orders-api.v1"}
+	assert.NoError(t, valid.Validate())
+	assert.NoError(t, (&ActivityConfig{}).Validate())
+
+	assertErrorCode(t, (&ActivityConfig{LevelThreshold: "TRACE"}).Validate(), ErrCodeInvalidLogLevel)
+	assertErrorCode(t, (&ActivityConfig{LogFormat: "yaml"}).Validate(), ErrCodeInvalidLogFormat)
+	assertErrorCode(t, (&ActivityConfig{LoggerName: "orders..api"}).Validate(), ErrCodeInvalidLoggerName)
+	assertErrorCode(t, (&ActivityConfig{LoggerName: "orders api"}).Validate(), ErrCodeInvalidLoggerName)
+	assertErrorCode(t, (&ActivityConfig{Sinks: "stdout,kafka"}).Validate(), ErrCodeInvalidSink)
+
+	t.Setenv(EnvKeySinks, "nowhere")
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidSink)
+
+	// the app-wide level is checked even when the activity overrides it
+	t.Setenv(EnvKeySinks, "")
+	t.Setenv(EnvKeyLogActivityLogLevel, "VERBOSE")
+	assertErrorCode(t, (&ActivityConfig{}).Validate(), ErrCodeInvalidLogLevel)
+	assertErrorCode(t, (&ActivityConfig{LevelThreshold: "ERROR"}).Validate(), ErrCodeInvalidLogLevel)
+}
+
+func TestValidateLogLevel(t *testing.T) {
+	for _, l := range []string{"INFO", "debug", "Warn", "ERROR"} {
+		assert.NoError(t, ValidateLogLevel(l))
+	}
+	assertErrorCode(t, ValidateLogLevel("FATAL"), ErrCodeInvalidLogLevel)
+}
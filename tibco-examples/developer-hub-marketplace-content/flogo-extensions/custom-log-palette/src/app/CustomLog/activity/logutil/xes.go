@@ -0,0 +1,73 @@
+package logutil
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+)
+
+// xesHeader declares the XES (IEEE 1849-2016) log with the concept and time extensions
+// and the activity classifier.
+const xesHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<log xes.version="1849-2016" xes.features="" xmlns="http://www.xes-standard.org/">
+  <extension name="Concept" prefix="concept" uri="http://www.xes-standard.org/concept.xesext"/>
+  <extension name="Time" prefix="time" uri="http://www.xes-standard.org/time.xesext"/>
+  <global scope="trace">
+    <string key="concept:name" value="__INVALID__"/>
+  </global>
+  <global scope="event">
+    <string key="concept:name" value="__INVALID__"/>
+    <date key="time:timestamp" value="1970-01-01T00:00:00.000+00:00"/>
+  </global>
+  <classifier name="Activity" keys="concept:name"/>
+`
+
+// WriteXES writes the log as an XES document with one trace per case, in order of the
+// case's first event; events within a trace are ordered by time. Attribute types follow
+// the Go type of the value (int, float, boolean, else string).
+func (l *EventLog) WriteXES(w io.Writer) error {
+	events := l.snapshot()
+	var cases []string
+	traces := make(map[string][]MiningEvent)
+	for _, ev := range events {
+		if _, ok := traces[ev.CaseID]; !ok {
+			cases = append(cases, ev.CaseID)
+		}
+		traces[ev.CaseID] = append(traces[ev.CaseID], ev)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xesHeader)
+	for _, id := range cases {
+		bw.WriteString("  <trace>\n")
+		writeXESAttribute(bw, "    ", "string", "concept:name", id)
+		for _, ev := range traces[id] {
+			bw.WriteString("    <event>\n")
+			writeXESAttribute(bw, "      ", "string", "concept:name", ev.Activity)
+			writeXESAttribute(bw, "      ", "date", "time:timestamp", eventLogTime(ev.Time))
+			for _, k := range OrderedKeys(ev.Attributes) {
+				v := ev.Attributes[k]
+				typ := eventLogValueType(v)
+				if typ == "integer" {
+					typ = "int"
+				}
+				writeXESAttribute(bw, "      ", typ, k, toString(v))
+			}
+			bw.WriteString("    </event>\n")
+		}
+		bw.WriteString("  </trace>\n")
+	}
+	bw.WriteString("</log>\n")
+	return bw.Flush()
+}
+
+func writeXESAttribute(w *bufio.Writer, indent, typ, key, value string) {
+	w.WriteString(indent)
+	w.WriteByte('<')
+	w.WriteString(typ)
+	w.WriteString(` key="`)
+	xml.EscapeText(w, []byte(key))
+	w.WriteString(`" value="`)
+	xml.EscapeText(w, []byte(value))
+	w.WriteString("\"/>\n")
+}
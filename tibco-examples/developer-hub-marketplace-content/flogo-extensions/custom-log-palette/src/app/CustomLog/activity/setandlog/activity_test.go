@@ -1,9 +1,17 @@
 package customlog
 
 import (
+	"bytes"
+	"strconv"
+	"sync"
 	"testing"
+
+	"github.com/extensions/customlogpalette/src/app/CustomLog/activity/logutil"
 	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/data"
+	"github.com/project-flogo/core/support/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRegister(t *testing.T) {
@@ -15,4 +23,150 @@ func TestRegister(t *testing.T) {
 }
 
 func TestEval(t *testing.T) {
+	var buf bytes.Buffer
+	logutil.RegisterSink("setandlog-test", func() (logutil.Sink, error) { return logutil.NewWriterSink(&buf), nil })
+	defer logutil.CloseSinks()
+
+	act, err := New(test.NewActivityInitContext(map[string]interface{}{"sinks": "setandlog-test", "logFormat": "json"}, nil))
+	require.NoError(t, err)
+
+	tc := test.NewActivityContext(act.Metadata())
+	tc.SetInput(ivLogLevel, "VERBOSE")
+	tc.SetInput(ivInputParams, map[string]interface{}{"message": "started"})
+	done, err := act.Eval(tc)
+	assert.False(t, done)
+	if actErr, ok := err.(*activity.Error); assert.True(t, ok) {
+		assert.Equal(t, logutil.ErrCodeInvalidLogLevel, actErr.Code())
+	}
+	assert.Empty(t, buf.String(), "an invalid level must not produce output")
+
+	tc.SetInput(ivLogLevel, "INFO")
+	done, err = act.Eval(tc)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Contains(t, buf.String(), `"a_message":"started"`)
+}
+
+func TestNewInvalidSettings(t *testing.T) {
+	_, err := New(test.NewActivityInitContext(map[string]interface{}{"logFormat": "xml"}, nil))
+	if actErr, ok := err.(*activity.Error); assert.True(t, ok) {
+		assert.Equal(t, logutil.ErrCodeInvalidLogFormat, actErr.Code())
+	}
+}
+
+// recordSink keeps the entries written to it.
+type recordSink struct {
+	mu      sync.Mutex
+	entries []*logutil.Entry
+}
+
+func (s *recordSink) Write(e *logutil.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *recordSink) Close() error {
+	return nil
+}
+
+func TestEvalParallelFlows(t *testing.T) {
+	sink := &recordSink{}
+	logutil.RegisterSink("setandlog-parallel", func() (logutil.Sink, error) { return sink, nil })
+	defer logutil.CloseSinks()
+
+	act, err := New(test.NewActivityInitContext(map[string]interface{}{"sinks": "setandlog-parallel"}, nil))
+	require.NoError(t, err)
+
+	const flows = 20
+	var wg sync.WaitGroup
+	for i := 0; i < flows; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			host := &test.TestActivityHost{HostId: id, HostData: data.NewSimpleScope(nil, nil)}
+			tc := test.NewActivityContextWithAction(act.Metadata(), host)
+			tc.SetInput(ivLogLevel, "INFO")
+			tc.SetInput(ivInputParams, map[string]interface{}{"message": "flow " + id})
+			for j := 0; j < 10; j++ {
+				_, err := act.Eval(tc)
+				assert.NoError(t, err)
+			}
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	require.Len(t, sink.entries, flows*10)
+	for _, e := range sink.entries {
+		assert.Equal(t, "flow "+e.Data["jobId"].(string), e.Data["message"])
+	}
+}
+
+func TestEvalTriggerHeaders(t *testing.T) {
+	sink := &recordSink{}
+	logutil.RegisterSink("setandlog-headers", func() (logutil.Sink, error) { return sink, nil })
+	defer logutil.CloseSinks()
+
+	act, err := New(test.NewActivityInitContext(map[string]interface{}{"sinks": "setandlog-headers"}, nil))
+	require.NoError(t, err)
+
+	host := &test.TestActivityHost{HostId: "headers-1", HostData: data.NewSimpleScope(nil, nil)}
+	t.Cleanup(func() { logutil.ReleaseFlowContext(host.HostId) })
+	tc := test.NewActivityContextWithAction(act.Metadata(), host)
+	tc.SetInput(ivLogLevel, "INFO")
+	tc.SetInput(ivTriggerHeaders, map[string]interface{}{
+		"traceparent":  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"baggage":      "tenant=acme",
+		"X-Request-ID": "req-7",
+	})
+	tc.SetInput(ivHeader, map[string]interface{}{"sessionId": "s-1"})
+	_, err = act.Eval(tc)
+	require.NoError(t, err)
+
+	require.Len(t, sink.entries, 1)
+	assert.Equal(t, "req-7", sink.entries[0].Data["correlationId"])
+	assert.Equal(t, "00f067aa0ba902b7", sink.entries[0].SpanID)
+
+	info := logutil.CustomFlowInfo(tc)
+	assert.Equal(t, "req-7", info["correlationId"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", info["traceID"])
+	assert.Equal(t, "acme", info["baggage.tenant"])
+	assert.Equal(t, "s-1", info["sessionId"])
+}
+
+func TestEvalGeneratedIDs(t *testing.T) {
+	sink := &recordSink{}
+	logutil.RegisterSink("setandlog-ids", func() (logutil.Sink, error) { return sink, nil })
+	defer logutil.CloseSinks()
+
+	_, err := New(test.NewActivityInitContext(map[string]interface{}{"idStrategy": "uuidv5"}, nil))
+	if actErr, ok := err.(*activity.Error); assert.True(t, ok) {
+		assert.Equal(t, logutil.ErrCodeInvalidIDStrategy, actErr.Code())
+	}
+
+	act, err := New(test.NewActivityInitContext(map[string]interface{}{"sinks": "setandlog-ids", "idStrategy": "ulid"}, nil))
+	require.NoError(t, err)
+
+	host := &test.TestActivityHost{HostId: "ids-1", HostData: data.NewSimpleScope(nil, nil)}
+	t.Cleanup(func() { logutil.ReleaseFlowContext(host.HostId) })
+	tc := test.NewActivityContextWithAction(act.Metadata(), host)
+	tc.SetInput(ivLogLevel, "INFO")
+	tc.SetInput(ivHeader, map[string]interface{}{"sessionId": "s-1"})
+	_, err = act.Eval(tc)
+	require.NoError(t, err)
+
+	assert.Equal(t, "s-1", tc.GetOutput(ovSessionId))
+	correlationID, _ := tc.GetOutput(ovCorrelationId).(string)
+	assert.Len(t, correlationID, 26)
+	assert.Len(t, tc.GetOutput(ovTrackingId), 26)
+	require.Len(t, sink.entries, 1)
+	assert.Equal(t, correlationID, sink.entries[0].Data["correlationId"])
+
+	// the next Set and Log of the flow keeps the generated IDs
+	tc.SetInput(ivHeader, map[string]interface{}{"sessionId": "s-2"})
+	_, err = act.Eval(tc)
+	require.NoError(t, err)
+	assert.Equal(t, correlationID, tc.GetOutput(ovCorrelationId))
+	assert.Equal(t, correlationID, logutil.CustomFlowInfo(tc)["correlationId"])
 }
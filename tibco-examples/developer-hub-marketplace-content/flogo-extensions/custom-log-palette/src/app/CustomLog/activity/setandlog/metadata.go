@@ -4,22 +4,42 @@ import (
 	"github.com/project-flogo/core/data/coerce"
 )
 
+type Settings struct {
+	Sinks          string `md:"sinks"`
+	LevelThreshold string `md:"levelThreshold"`
+	LogFormat      string `md:"logFormat"`
+	LoggerName     string `md:"loggerName"`
+	IDStrategy     string `md:"idStrategy"`
+}
+
 type Input struct {
-	LogLevel      string      `md:"Log Level"`
-	FlowInfo      bool        `md:"flowInfo"`
-	Header        interface{} `md:"Header"`
-	ContextParams interface{} `md:"contextParams"`
-	InputParams   interface{} `md:"Input"`
-	AdditionalLog interface{} `md:"additionalLogParams"`
+	LogLevel       string      `md:"Log Level"`
+	FlowInfo       bool        `md:"flowInfo"`
+	Header         interface{} `md:"Header"`
+	ContextParams  interface{} `md:"contextParams"`
+	TriggerHeaders interface{} `md:"triggerHeaders"`
+	InputParams    interface{} `md:"Input"`
+	AdditionalLog  interface{} `md:"additionalLogParams"`
+}
+
+type Output struct {
+	SessionId     string `md:"sessionId"`
+	CorrelationId string `md:"correlationId"`
+	TrackingId    string `md:"trackingId"`
 }
 
 const (
-	ivLogLevel      = "Log Level"
-	ivFlowInfo      = "flowInfo"
-	ivHeader        = "Header"
-	ivContextParams = "contextParams"
-	ivInputParams   = "Input"
-	ivAdditionalLog = "additionalLogParams"
+	ivLogLevel       = "Log Level"
+	ivFlowInfo       = "flowInfo"
+	ivHeader         = "Header"
+	ivContextParams  = "contextParams"
+	ivTriggerHeaders = "triggerHeaders"
+	ivInputParams    = "Input"
+	ivAdditionalLog  = "additionalLogParams"
+
+	ovSessionId     = "sessionId"
+	ovCorrelationId = "correlationId"
+	ovTrackingId    = "trackingId"
 )
 
 func (i *Input) ToMap() map[string]interface{} {
@@ -37,7 +57,32 @@ func (i *Input) FromMap(values map[string]interface{}) error {
 	i.FlowInfo, _ = coerce.ToBool(values[ivFlowInfo])
 	i.Header = values[ivHeader]
 	i.ContextParams = values[ivContextParams]
+	i.TriggerHeaders = values[ivTriggerHeaders]
 	i.InputParams = values[ivInputParams]
 	i.AdditionalLog = values[ivAdditionalLog]
 	return nil
 }
+
+// newOutput returns the identifiers of the stored context.
+func newOutput(info map[string]interface{}) *Output {
+	o := &Output{}
+	o.SessionId, _ = coerce.ToString(info[ovSessionId])
+	o.CorrelationId, _ = coerce.ToString(info[ovCorrelationId])
+	o.TrackingId, _ = coerce.ToString(info[ovTrackingId])
+	return o
+}
+
+func (o *Output) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		ovSessionId:     o.SessionId,
+		ovCorrelationId: o.CorrelationId,
+		ovTrackingId:    o.TrackingId,
+	}
+}
+
+func (o *Output) FromMap(values map[string]interface{}) error {
+	o.SessionId, _ = coerce.ToString(values[ovSessionId])
+	o.CorrelationId, _ = coerce.ToString(values[ovCorrelationId])
+	o.TrackingId, _ = coerce.ToString(values[ovTrackingId])
+	return nil
+}
@@ -0,0 +1,65 @@
+package updatecontext
+
+import (
+	"strings"
+
+	"github.com/extensions/customlogpalette/src/app/CustomLog/activity/logutil"
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/data/metadata"
+)
+
+// Activity changes the customFlowInfo of the flow instance (merge, replace or delete)
+// without writing a log line.
+type Activity struct {
+	mode string
+}
+
+var activityMd = activity.ToMetadata(&Settings{}, &Input{}, &Output{})
+
+// Metadata returns the activity's metadata
+func (a *Activity) Metadata() *activity.Metadata {
+	return activityMd
+}
+
+func init() {
+	_ = activity.Register(&Activity{}, New)
+}
+
+func New(ctx activity.InitContext) (activity.Activity, error) {
+	s := &Settings{}
+	err := metadata.MapToStruct(ctx.Settings(), s, true)
+	if err != nil {
+		return nil, err
+	}
+	// The mode is validated once, so an invalid setup fails at engine start
+	if err = logutil.ValidateContextMode(s.Mode); err != nil {
+		return nil, err
+	}
+	return &Activity{mode: s.Mode}, nil
+}
+
+// Eval implements api.Activity.Eval - Updates customFlowInfo
+func (a *Activity) Eval(context activity.Context) (done bool, err error) {
+	input := &Input{}
+	err = context.GetInputObject(input)
+	if err != nil {
+		return false, err
+	}
+
+	var keys []string
+	for _, k := range strings.Split(input.Keys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	info, err := logutil.UpdateCustomFlowInfo(context, logutil.ContextUpdate{
+		Mode:   a.mode,
+		Values: logutil.BuildCustomFlowInfoMap(input.Header, input.ContextParams),
+		Keys:   keys,
+		Scoped: input.Scoped,
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, context.SetOutputObject(&Output{CustomFlowInfo: info})
+}
@@ -0,0 +1,53 @@
+package updatecontext
+
+import (
+	"testing"
+
+	"github.com/extensions/customlogpalette/src/app/CustomLog/activity/logutil"
+	"github.com/project-flogo/core/activity"
+	"github.com/project-flogo/core/support/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+
+	ref := activity.GetRef(&Activity{})
+	act := activity.Get(ref)
+
+	assert.NotNil(t, act)
+}
+
+func TestNewInvalidMode(t *testing.T) {
+	// restore is not a mode: scoped updates are restored when their subflow ends
+	for _, mode := range []string{"upsert", "restore"} {
+		_, err := New(test.NewActivityInitContext(map[string]interface{}{"mode": mode}, nil))
+		require.Error(t, err, mode)
+		assert.Equal(t, logutil.ErrCodeInvalidContext, err.(*activity.Error).Code())
+	}
+}
+
+func TestEval(t *testing.T) {
+	act, err := New(test.NewActivityInitContext(map[string]interface{}{"mode": "merge"}, nil))
+	require.NoError(t, err)
+
+	tc := test.NewActivityContext(act.Metadata())
+	t.Cleanup(func() { logutil.ReleaseFlowContext(tc.ActivityHost().ID()) })
+	logutil.SetFlowVariable(tc, logutil.CustomFlowInfoKey, map[string]interface{}{"correlationId": "c-1"})
+	tc.SetInput(ivHeader, map[string]interface{}{"sessionId": "s-1"})
+	tc.SetInput(ivContextParams, map[string]interface{}{"keyValuePair": []interface{}{map[string]interface{}{"name": "orderId", "value": "o-9"}}})
+	done, err := act.Eval(tc)
+	require.NoError(t, err)
+	assert.True(t, done)
+
+	want := map[string]interface{}{"correlationId": "c-1", "sessionId": "s-1", "orderId": "o-9"}
+	assert.Equal(t, want, logutil.CustomFlowInfo(tc))
+	assert.Equal(t, want, tc.GetOutput(ovCustomFlowInfo))
+
+	del, err := New(test.NewActivityInitContext(map[string]interface{}{"mode": "delete"}, nil))
+	require.NoError(t, err)
+	tc.SetInput(ivKeys, "sessionId, orderId")
+	_, err = del.Eval(tc)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"correlationId": "c-1"}, logutil.CustomFlowInfo(tc))
+}
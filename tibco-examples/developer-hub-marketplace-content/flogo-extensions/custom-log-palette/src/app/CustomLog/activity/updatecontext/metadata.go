@@ -0,0 +1,57 @@
+package updatecontext
+
+import (
+	"github.com/project-flogo/core/data/coerce"
+)
+
+type Settings struct {
+	Mode string `md:"mode"`
+}
+
+type Input struct {
+	Header        interface{} `md:"Header"`
+	ContextParams interface{} `md:"contextParams"`
+	Keys          string      `md:"keys"`
+	Scoped        bool        `md:"scoped"`
+}
+
+type Output struct {
+	CustomFlowInfo map[string]interface{} `md:"customFlowInfo"`
+}
+
+const (
+	ivHeader        = "Header"
+	ivContextParams = "contextParams"
+	ivKeys          = "keys"
+	ivScoped        = "scoped"
+
+	ovCustomFlowInfo = "customFlowInfo"
+)
+
+func (i *Input) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		ivHeader:        i.Header,
+		ivContextParams: i.ContextParams,
+		ivKeys:          i.Keys,
+		ivScoped:        i.Scoped,
+	}
+}
+
+func (i *Input) FromMap(values map[string]interface{}) error {
+	i.Header = values[ivHeader]
+	i.ContextParams = values[ivContextParams]
+	i.Keys, _ = coerce.ToString(values[ivKeys])
+	i.Scoped, _ = coerce.ToBool(values[ivScoped])
+	return nil
+}
+
+func (o *Output) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		ovCustomFlowInfo: o.CustomFlowInfo,
+	}
+}
+
+func (o *Output) FromMap(values map[string]interface{}) error {
+	o.CustomFlowInfo, _ = coerce.ToObject(values[ovCustomFlowInfo])
+	return nil
+}